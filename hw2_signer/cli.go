@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type config struct {
+	// max items hashed concurrently on every stage, 0 is unlimited
+	limit int
+	// combine all results into one line
+	combine bool
+	// output format, plain or json
+	format string
+	// journal file to resume interrupted runs from, optional
+	journal string
+	// worker addresses and names of the jobs to run on them
+	remote        []string
+	remoteJobs    map[string]bool
+	remoteTimeout time.Duration
+	// file to write trace dump to, - is stderr, optional
+	trace string
+}
+
+type lineResult struct {
+	Input  string `json:"input"`
+	Result string `json:"result"`
+}
+
+type combinedResult struct {
+	Count  int    `json:"count"`
+	Result string `json:"result"`
+}
+
+// readLines sends every non empty line of r to out
+func readLines(r io.Reader, out chan interface{}, seen func(string)) error {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		seen(line)
+		out <- line
+	}
+	return sc.Err()
+}
+
+// run signs lines of files (or stdin if there are no files) and writes results to w
+func run(cfg config, files []string, stdin io.Reader, w io.Writer) error {
+	switch cfg.format {
+	case "plain", "json":
+	default:
+		return fmt.Errorf("unknown format %q", cfg.format)
+	}
+
+	var (
+		mu      sync.Mutex
+		inputs  []string
+		readErr error
+		outErr  error
+	)
+	seen := func(s string) {
+		mu.Lock()
+		inputs = append(inputs, s)
+		mu.Unlock()
+	}
+
+	jobs := []job{
+		job(func(in, out chan interface{}) {
+			if len(files) == 0 {
+				readErr = readLines(stdin, out, seen)
+				return
+			}
+			for _, fp := range files {
+				f, err := os.Open(fp)
+				if err != nil {
+					readErr = err
+					return
+				}
+				err = readLines(f, out, seen)
+				f.Close()
+				if err != nil {
+					readErr = fmt.Errorf("%s: %w", fp, err)
+					return
+				}
+			}
+		}),
+	}
+	for name := range cfg.remoteJobs {
+		if _, ok := remoteJobs[name]; !ok {
+			return fmt.Errorf("unknown remote job %q", name)
+		}
+	}
+	var tracer *Tracer
+	if cfg.trace != "" {
+		tracer = NewTracer()
+		jobs = append(jobs, tracer.Wrap())
+	}
+
+	var remote *Remote
+	if len(cfg.remote) > 0 {
+		var err error
+		remote, err = DialWorkers(cfg.remote...)
+		if err != nil {
+			return err
+		}
+		defer remote.Close()
+		remote.Timeout = cfg.remoteTimeout
+	}
+	stage := func(name string, local func(int) job) job {
+		if remote != nil && cfg.remoteJobs[name] {
+			return remote.Job(name, cfg.limit)
+		}
+		return local(cfg.limit)
+	}
+	stages := []job{stage("SingleHash", SingleHashN), stage("MultiHash", MultiHashN)}
+
+	var journal *Journal
+	if cfg.journal != "" {
+		var err error
+		journal, err = OpenJournal(cfg.journal)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, journal.Checkpoint(cfg.limit, stages...))
+	} else {
+		jobs = append(jobs, stages...)
+	}
+	if cfg.combine {
+		jobs = append(jobs, job(CombineResults))
+	}
+	if tracer != nil {
+		jobs = append(jobs, tracer.Unwrap())
+	}
+
+	enc := json.NewEncoder(w)
+	jobs = append(jobs, job(func(in, out chan interface{}) {
+		n := 0
+		for r := range in {
+			// results after worker failure could be attributed to wrong inputs
+			if outErr != nil || (remote != nil && remote.Err() != nil) {
+				continue
+			}
+			res := r.(string)
+			switch {
+			case cfg.format == "plain":
+				_, outErr = fmt.Fprintln(w, res)
+			case cfg.combine:
+				mu.Lock()
+				count := len(inputs)
+				mu.Unlock()
+				outErr = enc.Encode(combinedResult{Count: count, Result: res})
+			default:
+				mu.Lock()
+				input := inputs[n]
+				mu.Unlock()
+				outErr = enc.Encode(lineResult{Input: input, Result: res})
+			}
+			n++
+		}
+	}))
+
+	ExecutePipeline(jobs...)
+
+	if journal != nil {
+		if err := journal.Close(); err != nil {
+			return err
+		}
+	}
+	if readErr != nil {
+		return readErr
+	}
+	if remote != nil && remote.Err() != nil {
+		return remote.Err()
+	}
+	if outErr != nil {
+		return outErr
+	}
+	if tracer != nil {
+		return dumpTrace(tracer, cfg.trace)
+	}
+	return nil
+}
+
+func dumpTrace(t *Tracer, fp string) error {
+	if fp == "-" {
+		return t.Dump(os.Stderr)
+	}
+	f, err := os.Create(fp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = t.Dump(w)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// splitList splits comma separated flag value
+func splitList(s string) []string {
+	rv := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			rv = append(rv, v)
+		}
+	}
+	return rv
+}
+
+// serve runs worker process on addr
+func serve(addr string) error {
+	l, err := ListenWorker(addr)
+	if err != nil {
+		return err
+	}
+	// closing listener removes unix socket file
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
+	go func() {
+		<-stop
+		close(stopped)
+		l.Close()
+	}()
+
+	fmt.Fprintln(os.Stderr, "worker is listening on", addr)
+	err = ServeWorker(l, remoteJobs)
+	select {
+	case <-stopped:
+		return nil
+	default:
+	}
+	return err
+}
+
+func main() {
+	cfg := config{}
+	var serveAddr, remote, remoteJobList string
+	flag.StringVar(&DataSignerSalt, "salt", DataSignerSalt, "salt appended to data before hashing")
+	flag.IntVar(&cfg.limit, "c", 0, "max items hashed concurrently on every stage, 0 is unlimited")
+	flag.BoolVar(&cfg.combine, "combine", false, "combine all results into one")
+	flag.StringVar(&cfg.format, "format", "plain", "output format: plain or json (one object per line)")
+	flag.StringVar(&cfg.journal, "journal", "", "journal file, finished items are recorded there and skipped on restart")
+	flag.StringVar(&cfg.trace, "trace", "", "write lineage of every result to file, - is stderr")
+	flag.StringVar(&serveAddr, "serve", "", "run as worker on unix:path or tcp:host:port, coordinator refuses worker with another salt")
+	flag.StringVar(&remote, "remote", "", "comma separated worker addresses to run remote jobs on")
+	flag.StringVar(&remoteJobList, "remote-jobs", "MultiHash", "comma separated jobs to run on workers: SingleHash, MultiHash")
+	flag.DurationVar(&cfg.remoteTimeout, "remote-timeout", time.Minute, "max time to wait for worker reply to a single item, 0 is forever")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [file ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if serveAddr != "" {
+		if err := serve(serveAddr); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg.remote = splitList(remote)
+	cfg.remoteJobs = map[string]bool{}
+	for _, name := range splitList(remoteJobList) {
+		cfg.remoteJobs[name] = true
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	err := run(cfg, flag.Args(), os.Stdin, w)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config
+		in   string
+		want string
+	}{
+		{"plain", config{format: "plain", limit: 1}, "0\n\n1\n",
+			"29568666068035183841425683795340791879727309630931025356555\n" +
+				"4958044192186797981418233587017209679042592862002427381542\n"},
+		{"json", config{format: "json", combine: true}, "1\r\n0",
+			`{"count":2,"result":"29568666068035183841425683795340791879727309630931025356555_4958044192186797981418233587017209679042592862002427381542"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			var err error
+			withClock(instantClock{}, func() { err = run(tt.cfg, nil, strings.NewReader(tt.in), out) })
+			if err != nil {
+				t.Fatalf("run() error = %v", err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("run() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunLines(t *testing.T) {
+	inputs := []string{"0", "1", "2", "10", "abc", "7", "42", "1"}
+	want := map[string]string{}
+	withClock(instantClock{}, func() {
+		for _, in := range inputs {
+			want[in] = multiHash(singleHash(in))
+		}
+	})
+
+	out := new(bytes.Buffer)
+	var err error
+	withClock(instantClock{}, func() {
+		err = run(config{format: "json", limit: 3}, nil, strings.NewReader(strings.Join(inputs, "\n")), out)
+	})
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	dec := json.NewDecoder(out)
+	for n, in := range inputs {
+		var got lineResult
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("line %d: %v", n, err)
+		}
+		if got.Input != in || got.Result != want[in] {
+			t.Errorf("line %d = %+v, want {%s %s}", n, got, in, want[in])
+		}
+	}
+	if dec.More() {
+		t.Errorf("run() wrote more than %d lines", len(inputs))
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	if err := run(config{format: "xml"}, nil, strings.NewReader(""), new(bytes.Buffer)); err == nil {
+		t.Errorf("run() expected error for unknown format")
+	}
+	if err := run(config{format: "plain"}, []string{"no such file"}, nil, new(bytes.Buffer)); err == nil {
+		t.Errorf("run() expected error for missing file")
+	}
+}
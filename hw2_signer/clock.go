@@ -0,0 +1,126 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time for data signers and pipeline timeouts
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+	After(d time.Duration) <-chan time.Time
+}
+
+// SignerClock is used by data signers, tests replace it with FakeClock
+var SignerClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type fakeWaiter struct {
+	until time.Time
+	ch    chan time.Time
+}
+
+// FakeClock is a Clock that moves only when Advance or AdvanceNext is called
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+	// signaled on every new waiter
+	added *sync.Cond
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.added = sync.NewCond(&c.mu)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{until: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- c.now
+		return w.ch
+	}
+	c.waiters = append(c.waiters, w)
+	sort.SliceStable(c.waiters, func(i, j int) bool { return c.waiters[i].until.Before(c.waiters[j].until) })
+	c.added.Broadcast()
+	return w.ch
+}
+
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// Waiters returns number of pending Sleep and After calls
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// BlockUntil blocks until there are at least n pending Sleep and After calls,
+// so the caller knows that goroutines it is going to wake up are waiting already.
+// it gives up after timeout of real time and returns false then
+func (c *FakeClock) BlockUntil(n int, timeout time.Duration) bool {
+	expired := false
+	t := time.AfterFunc(timeout, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		expired = true
+		c.added.Broadcast()
+	})
+	defer t.Stop()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n && !expired {
+		c.added.Wait()
+	}
+	return len(c.waiters) >= n
+}
+
+// Advance moves clock forward by d and wakes up everyone whose time has come
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advanceTo(c.now.Add(d))
+}
+
+// AdvanceNext moves clock to the earliest pending deadline,
+// it returns false if there is nobody to wake up
+func (c *FakeClock) AdvanceNext() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.waiters) == 0 {
+		return false
+	}
+	c.advanceTo(c.waiters[0].until)
+	return true
+}
+
+func (c *FakeClock) advanceTo(t time.Time) {
+	if t.After(c.now) {
+		c.now = t
+	}
+	n := 0
+	for n < len(c.waiters) && !c.waiters[n].until.After(c.now) {
+		c.waiters[n].ch <- c.now
+		n++
+	}
+	c.waiters = c.waiters[n:]
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// withClock runs f with SignerClock replaced by c
+func withClock(c Clock, f func()) {
+	prev := SignerClock
+	SignerClock = c
+	defer func() { SignerClock = prev }()
+	f()
+}
+
+// goWithClock runs f with SignerClock replaced by c in a new goroutine,
+// the returned channel is closed when f returns. the caller moves the clock
+func goWithClock(c Clock, f func()) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		withClock(c, f)
+		close(done)
+	}()
+	return done
+}
+
+// instantClock never waits, it is for tests that dont check timing
+type instantClock struct{}
+
+func (instantClock) Now() time.Time        { return time.Unix(0, 0) }
+func (instantClock) Sleep(d time.Duration) {}
+func (instantClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- time.Unix(0, 0)
+	return ch
+}
+
+const (
+	// blockTimeout is how long tests wait for goroutines to sleep on fake clock,
+	// the ones that dont are stuck
+	blockTimeout = time.Second
+	// settleTime is how long clock waiters dont change when all goroutines are asleep
+	settleTime = 20 * time.Millisecond
+)
+
+// blockUntil is FakeClock.BlockUntil failing t instead of waiting forever
+func blockUntil(t *testing.T, clk *FakeClock, n int) {
+	t.Helper()
+	if !clk.BlockUntil(n, blockTimeout) {
+		t.Fatalf("%d goroutines sleep on clock after %s, want %d", clk.Waiters(), blockTimeout, n)
+	}
+}
+
+// advanceUntilDone moves clk to the next deadline every time goroutines settle
+// until done is closed, and returns how far clk was moved. so it is as long as
+// the code takes with its real concurrency, sequential code takes longer
+func advanceUntilDone(t *testing.T, clk *FakeClock, done <-chan struct{}) time.Duration {
+	t.Helper()
+	start := clk.Now()
+	deadline := time.Now().Add(blockTimeout)
+	for {
+		select {
+		case <-done:
+			return clk.Now().Sub(start)
+		default:
+		}
+		if !clk.BlockUntil(1, settleTime) {
+			if time.Now().After(deadline) {
+				t.Fatalf("nothing sleeps on clock for %s and code is not done", blockTimeout)
+			}
+			continue
+		}
+		// goroutines woken up by the previous move start to sleep again
+		for clk.BlockUntil(clk.Waiters()+1, settleTime) {
+		}
+		clk.AdvanceNext()
+		deadline = time.Now().Add(blockTimeout)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Unix(0, 0)
+	clk := NewFakeClock(start)
+
+	a := clk.After(2 * time.Second)
+	b := clk.After(time.Second)
+	select {
+	case <-clk.After(0):
+	default:
+		t.Fatalf("After(0) should fire immediately")
+	}
+	if got := clk.Waiters(); got != 2 {
+		t.Fatalf("Waiters() = %d, want 2", got)
+	}
+
+	clk.Advance(500 * time.Millisecond)
+	select {
+	case <-a:
+		t.Fatalf("a fired too early")
+	case <-b:
+		t.Fatalf("b fired too early")
+	default:
+	}
+
+	if !clk.AdvanceNext() {
+		t.Fatalf("AdvanceNext() = false, want true")
+	}
+	if got := (<-b).Sub(start); got != time.Second {
+		t.Errorf("b fired at %s, want 1s", got)
+	}
+
+	// After called by another goroutine is seen by BlockUntil
+	c := make(chan (<-chan time.Time), 1)
+	go func() { c <- clk.After(time.Second) }()
+	if !clk.BlockUntil(2, time.Second) {
+		t.Fatalf("BlockUntil(2) = false, want true")
+	}
+	if clk.BlockUntil(3, 10*time.Millisecond) {
+		t.Fatalf("BlockUntil(3) = true with 2 waiters")
+	}
+
+	clk.Advance(5 * time.Second)
+	if got := (<-a).Sub(start); got != 6*time.Second {
+		t.Errorf("a fired at %s, want 6s", got)
+	}
+	if got := (<-<-c).Sub(start); got != 6*time.Second {
+		t.Errorf("c fired at %s, want 6s", got)
+	}
+	if clk.AdvanceNext() {
+		t.Errorf("AdvanceNext() = true without waiters")
+	}
+	if got := clk.Now().Sub(start); got != 6*time.Second {
+		t.Errorf("Now() = %s, want 6s", got)
+	}
+}
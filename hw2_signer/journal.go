@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// journalHeader is the first line of journal file. results depend on salt,
+// so journal written with another one cant be resumed
+type journalHeader struct {
+	Salt *string `json:"salt"`
+}
+
+// journalRecord is a single line of journal file, n is the index of input item
+type journalRecord struct {
+	N   int      `json:"n"`
+	In  string   `json:"in"`
+	Out []string `json:"out"`
+}
+
+// Journal is an append only file with results of the items
+// that have passed all the checkpointed stages
+type Journal struct {
+	mu   sync.Mutex
+	f    *os.File
+	done map[int]journalRecord
+	err  error
+}
+
+// OpenJournal opens or creates journal file. records written by
+// a previous run are loaded, a partially written last record is dropped.
+// a file that isnt a journal or was written with salt other than
+// DataSignerSalt is an error and is left as is
+func OpenJournal(fp string) (*Journal, error) {
+	f, err := os.OpenFile(fp, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{f: f, done: make(map[int]journalRecord)}
+	if err := j.load(fp); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return j, nil
+}
+
+// load reads records of journal and leaves file ready to append,
+// header is written if there is none yet
+func (j *Journal) load(fp string) error {
+	header, _ := json.Marshal(journalHeader{Salt: &DataSignerSalt})
+	header = append(header, '\n')
+
+	var valid int64
+	r := bufio.NewReader(j.f)
+	for n := 0; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		// every line is written with a single write ending with newline,
+		// so only the last one can be partial
+		if err == io.EOF {
+			if n == 0 && !bytes.HasPrefix(header, line) {
+				return fmt.Errorf("%s: not a journal", fp)
+			}
+			break
+		}
+		if n == 0 {
+			h := journalHeader{}
+			if json.Unmarshal(line, &h) != nil || h.Salt == nil {
+				return fmt.Errorf("%s: not a journal", fp)
+			}
+			if *h.Salt != DataSignerSalt {
+				return fmt.Errorf("%s: journal was written with another salt", fp)
+			}
+			valid += int64(len(line))
+			continue
+		}
+		rec := journalRecord{}
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fmt.Errorf("%s: line %d: %w", fp, n+1, err)
+		}
+		j.done[rec.N] = rec
+		valid += int64(len(line))
+	}
+
+	if err := j.f.Truncate(valid); err != nil {
+		return err
+	}
+	if _, err := j.f.Seek(valid, io.SeekStart); err != nil {
+		return err
+	}
+	if valid == 0 {
+		if _, err := j.f.Write(header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Done returns number of items recorded in journal
+func (j *Journal) Done() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.done)
+}
+
+// Err returns the first error happened while writing journal
+func (j *Journal) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+func (j *Journal) Close() error {
+	err := j.f.Close()
+	if jerr := j.Err(); jerr != nil {
+		return jerr
+	}
+	return err
+}
+
+func (j *Journal) lookup(n int, in string) ([]string, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	rec, ok := j.done[n]
+	if !ok || rec.In != in {
+		return nil, false
+	}
+	return rec.Out, true
+}
+
+func (j *Journal) record(rec journalRecord) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		panic(err)
+	}
+	line = append(line, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.err != nil {
+		return
+	}
+	if _, err := j.f.Write(line); err != nil {
+		j.err = fmt.Errorf("journal write: %w", err)
+		return
+	}
+	if err := j.f.Sync(); err != nil {
+		j.err = fmt.Errorf("journal sync: %w", err)
+		return
+	}
+	j.done[rec.N] = rec
+}
+
+// runItem passes a single item through stages and collects what comes out
+func runItem(item interface{}, stages ...job) []interface{} {
+	rv := make([]interface{}, 0, 1)
+	jobs := make([]job, 0, len(stages)+2)
+	jobs = append(jobs, job(func(in, out chan interface{}) {
+		out <- item
+	}))
+	jobs = append(jobs, stages...)
+	jobs = append(jobs, job(func(in, out chan interface{}) {
+		for r := range in {
+			rv = append(rv, r)
+		}
+	}))
+	ExecutePipeline(jobs...)
+	return rv
+}
+
+// Checkpoint returns a job that runs every input item through stages on its own,
+// at most limit items concurrently (zero means no limit). string results are
+// recorded to journal unless there are none, items already recorded with the same input at the same
+// position are not recomputed and their results are replayed instead.
+// results are sent in the input order
+func (j *Journal) Checkpoint(limit int, stages ...job) job {
+	return parallel(limit, func(n int, item interface{}) []interface{} {
+		key, ok := itemValue(item)
+		if !ok {
+			return runItem(item, stages...)
+		}
+		if res, ok := j.lookup(n, key); ok {
+			return withResults(item, "Journal", SignerClock.Now(), res...)
+		}
+
+		rv := runItem(item, stages...)
+		rec := journalRecord{N: n, In: key, Out: make([]string, 0, len(rv))}
+		for _, r := range rv {
+			if s, ok := itemValue(r); ok {
+				rec.Out = append(rec.Out, s)
+			}
+		}
+		// nothing came out, the item hasnt finished stages
+		if len(rec.Out) > 0 {
+			j.record(rec)
+		}
+		return rv
+	})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestJournalResume(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "journal")
+	input := []string{"b", "a", "b", "c"}
+
+	var calls uint32
+	upper := job(func(in, out chan interface{}) {
+		for i := range in {
+			atomic.AddUint32(&calls, 1)
+			out <- strings.ToUpper(i.(string))
+		}
+	})
+
+	runOnce := func(input []string) (string, uint32) {
+		j, err := OpenJournal(fp)
+		if err != nil {
+			t.Fatalf("OpenJournal() error = %v", err)
+		}
+		defer j.Close()
+
+		atomic.StoreUint32(&calls, 0)
+		var result string
+		ExecutePipeline(
+			job(func(in, out chan interface{}) {
+				for _, s := range input {
+					out <- s
+				}
+			}),
+			j.Checkpoint(2, upper),
+			job(CombineResults),
+			job(func(in, out chan interface{}) {
+				result = (<-in).(string)
+			}),
+		)
+		return result, atomic.LoadUint32(&calls)
+	}
+
+	// crash after first two items, last record is written partially
+	if _, calls := runOnce(input[:2]); calls != 2 {
+		t.Fatalf("first run calls = %d, want 2", calls)
+	}
+	f, err := os.OpenFile(fp, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"n":2,"in":"b","ou`)
+	f.Close()
+
+	got, calls := runOnce(input)
+	if want := "A_B_B_C"; got != want {
+		t.Errorf("resumed result = %v, want %v", got, want)
+	}
+	if calls != 2 {
+		t.Errorf("resumed run calls = %d, want 2", calls)
+	}
+
+	got, calls = runOnce(input)
+	if want := "A_B_B_C"; got != want || calls != 0 {
+		t.Errorf("finished run = %v, %d calls, want %v, 0 calls", got, calls, want)
+	}
+
+	j, err := OpenJournal(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if got := j.Done(); got != len(input) {
+		t.Errorf("Done() = %d, want %d", got, len(input))
+	}
+	if out, ok := j.lookup(3, "c"); !ok || !reflect.DeepEqual(out, []string{"C"}) {
+		t.Errorf("lookup(3, c) = %v, %v", out, ok)
+	}
+	if _, ok := j.lookup(3, "d"); ok {
+		t.Errorf("lookup(3, d) found record for different input")
+	}
+}
+
+func TestJournalSalt(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "journal")
+	prev := DataSignerSalt
+	defer func() { DataSignerSalt = prev }()
+
+	DataSignerSalt = "a"
+	j, err := OpenJournal(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.record(journalRecord{N: 0, In: "x", Out: []string{"X"}})
+	j.Close()
+
+	DataSignerSalt = "b"
+	if _, err := OpenJournal(fp); err == nil {
+		t.Errorf("OpenJournal() with another salt error = nil")
+	}
+
+	DataSignerSalt = "a"
+	j, err = OpenJournal(fp)
+	if err != nil {
+		t.Fatalf("OpenJournal() error = %v", err)
+	}
+	defer j.Close()
+	if _, ok := j.lookup(0, "x"); !ok {
+		t.Errorf("lookup(0, x) not found after reopen")
+	}
+
+	// records without header arent a journal
+	os.WriteFile(fp, []byte(`{"n":0,"in":"x","out":["X"]}`+"\n"), 0644)
+	if _, err := OpenJournal(fp); err == nil {
+		t.Errorf("OpenJournal() without header error = nil")
+	}
+}
+
+func TestJournalForeignFile(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "journal")
+	header := `{"salt":"` + DataSignerSalt + `"}` + "\n"
+
+	for _, data := range []string{
+		"hello",
+		"hello\n",
+		header + "hello\n" + `{"n":0,"in":"x","out":["X"]}` + "\n",
+	} {
+		os.WriteFile(fp, []byte(data), 0644)
+		if _, err := OpenJournal(fp); err == nil {
+			t.Errorf("OpenJournal(%q) error = nil", data)
+		}
+		if got, _ := os.ReadFile(fp); string(got) != data {
+			t.Errorf("OpenJournal(%q) changed file to %q", data, got)
+		}
+	}
+
+	// header write was interrupted
+	os.WriteFile(fp, []byte(header[:5]), 0644)
+	j, err := OpenJournal(fp)
+	if err != nil {
+		t.Fatalf("OpenJournal() of partial header error = %v", err)
+	}
+	j.Close()
+	if got, _ := os.ReadFile(fp); string(got) != header {
+		t.Errorf("journal = %q, want %q", got, header)
+	}
+}
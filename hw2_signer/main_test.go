@@ -65,7 +65,7 @@ func TestSigner(t *testing.T) {
 		for {
 			if swapped := atomic.CompareAndSwapUint32(&dataSignerOverheat, 0, 1); !swapped {
 				fmt.Println("OverheatLock happend")
-				time.Sleep(time.Second)
+				SignerClock.Sleep(time.Second)
 			} else {
 				break
 			}
@@ -76,7 +76,7 @@ func TestSigner(t *testing.T) {
 		for {
 			if swapped := atomic.CompareAndSwapUint32(&dataSignerOverheat, 1, 0); !swapped {
 				fmt.Println("OverheatUnlock happend")
-				time.Sleep(time.Second)
+				SignerClock.Sleep(time.Second)
 			} else {
 				break
 			}
@@ -88,7 +88,7 @@ func TestSigner(t *testing.T) {
 		defer OverheatUnlock()
 		data += DataSignerSalt
 		dataHash := fmt.Sprintf("%x", md5.Sum([]byte(data)))
-		time.Sleep(10 * time.Millisecond)
+		SignerClock.Sleep(10 * time.Millisecond)
 		return dataHash
 	}
 	DataSignerCrc32 = func(data string) string {
@@ -96,7 +96,7 @@ func TestSigner(t *testing.T) {
 		data += DataSignerSalt
 		crcH := crc32.ChecksumIEEE([]byte(data))
 		dataHash := strconv.FormatUint(uint64(crcH), 10)
-		time.Sleep(time.Second)
+		SignerClock.Sleep(time.Second)
 		return dataHash
 	}
 
@@ -122,11 +122,13 @@ func TestSigner(t *testing.T) {
 		}),
 	}
 
-	start := time.Now()
-
-	ExecutePipeline(hashSignJobs...)
-
-	end := time.Since(start)
+	// время считается по фейковым часам, тест не ждет реальные секунды
+	start := time.Unix(0, 0)
+	clk := NewFakeClock(start)
+	done := goWithClock(clk, func() {
+		ExecutePipeline(hashSignJobs...)
+	})
+	end := advanceUntilDone(t, clk, done)
 
 	expectedTime := 3 * time.Second
 
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// remoteJobs are the jobs worker process runs for coordinator
+var remoteJobs = map[string]job{
+	"SingleHash": SingleHash,
+	"MultiHash":  MultiHash,
+}
+
+type WorkArgs struct {
+	Job  string
+	Item string
+}
+
+type WorkReply struct {
+	Out []string
+}
+
+// Worker is net/rpc service passing items through its jobs
+type Worker struct {
+	jobs map[string]job
+	salt string
+}
+
+// Salt replies with salt worker hashes with, coordinator checks it is its own
+func (w *Worker) Salt(_ struct{}, reply *string) error {
+	*reply = w.salt
+	return nil
+}
+
+func (w *Worker) Run(args WorkArgs, reply *WorkReply) error {
+	j, ok := w.jobs[args.Job]
+	if !ok {
+		return fmt.Errorf("unknown job %q", args.Job)
+	}
+	for _, r := range runItem(args.Item, j) {
+		if s, ok := r.(string); ok {
+			reply.Out = append(reply.Out, s)
+		}
+	}
+	return nil
+}
+
+// newWorkerServer makes server of Worker hashing with the current DataSignerSalt
+func newWorkerServer(jobs map[string]job) *rpc.Server {
+	srv := rpc.NewServer()
+	if err := srv.Register(&Worker{jobs: jobs, salt: DataSignerSalt}); err != nil {
+		panic(err)
+	}
+	return srv
+}
+
+// ServeWorker serves Worker with jobs on connections accepted from l until l is closed
+func ServeWorker(l net.Listener, jobs map[string]job) error {
+	srv := newWorkerServer(jobs)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.ServeConn(conn)
+	}
+}
+
+// parseAddr splits address like unix:/tmp/worker.sock or tcp:127.0.0.1:9000
+func parseAddr(addr string) (network, address string, err error) {
+	network, address, ok := strings.Cut(addr, ":")
+	if !ok || address == "" {
+		return "", "", fmt.Errorf("bad address %q, want unix:path or tcp:host:port", addr)
+	}
+	switch network {
+	case "unix", "tcp", "tcp4", "tcp6":
+	default:
+		return "", "", fmt.Errorf("bad network %q in address %q", network, addr)
+	}
+	return network, address, nil
+}
+
+// ListenWorker listens on address in parseAddr format
+func ListenWorker(addr string) (net.Listener, error) {
+	network, address, err := parseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return net.Listen(network, address)
+}
+
+// Remote is a coordinator side of connections to worker processes
+type Remote struct {
+	// Timeout is how long a single item waits for worker reply, zero means forever.
+	// it is measured by SignerClock
+	Timeout time.Duration
+
+	addrs   []string
+	clients []*rpc.Client
+	next    uint32
+
+	mu  sync.Mutex
+	err error
+}
+
+// DialWorkers connects to every worker, items are spread between them round robin.
+// worker with salt other than DataSignerSalt is an error, its results would differ
+func DialWorkers(addrs ...string) (*Remote, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no workers")
+	}
+	r := &Remote{addrs: addrs}
+	for _, addr := range addrs {
+		network, address, err := parseAddr(addr)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		c, err := rpc.Dial(network, address)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.clients = append(r.clients, c)
+
+		var salt string
+		if err := c.Call("Worker.Salt", struct{}{}, &salt); err != nil {
+			r.Close()
+			return nil, fmt.Errorf("worker %s: %w", addr, err)
+		}
+		if salt != DataSignerSalt {
+			r.Close()
+			return nil, fmt.Errorf("worker %s: salt differs from coordinator one", addr)
+		}
+	}
+	return r, nil
+}
+
+func (r *Remote) Close() error {
+	var rv error
+	for _, c := range r.clients {
+		if err := c.Close(); err != nil && rv == nil {
+			rv = err
+		}
+	}
+	return rv
+}
+
+// Err returns the first worker failure, items are not sent anymore after it
+func (r *Remote) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Remote) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// Job returns job that sends every input item to workers to be passed through
+// remote job name, at most limit items are in flight (zero means no limit).
+// results are sent in the input order. if a worker fails or doesnt reply in Timeout
+// the rest of input is drained without processing and the failure is reported by Err
+func (r *Remote) Job(name string, limit int) job {
+	return parallel(limit, func(_ int, item interface{}) []interface{} {
+		s, ok := itemValue(item)
+		if !ok || r.Err() != nil {
+			return nil
+		}
+		start := SignerClock.Now()
+
+		k := int(atomic.AddUint32(&r.next, 1)-1) % len(r.clients)
+		reply := WorkReply{}
+		call := r.clients[k].Go("Worker.Run", WorkArgs{Job: name, Item: s}, &reply, make(chan *rpc.Call, 1))
+		var timeout <-chan time.Time
+		if r.Timeout > 0 {
+			timeout = SignerClock.After(r.Timeout)
+		}
+		select {
+		case <-call.Done:
+			if call.Error != nil {
+				r.fail(fmt.Errorf("worker %s: %s: %w", r.addrs[k], name, call.Error))
+				return nil
+			}
+		case <-timeout:
+			r.fail(fmt.Errorf("worker %s: %s: no reply in %s", r.addrs[k], name, r.Timeout))
+			return nil
+		}
+
+		return withResults(item, name, start, reply.Out...)
+	})
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"net"
+	"net/rpc"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var upperJob = job(func(in, out chan interface{}) {
+	for i := range in {
+		out <- strings.ToUpper(i.(string))
+	}
+})
+
+// serveOne serves the first connection accepted from l with jobs made for it
+func serveOne(l net.Listener, jobs func(conn net.Conn) map[string]job) {
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		newWorkerServer(jobs(conn)).ServeConn(conn)
+	}()
+}
+
+func runRemote(r *Remote, name string, input ...interface{}) []string {
+	got := []string{}
+	ExecutePipeline(
+		job(func(in, out chan interface{}) {
+			for _, i := range input {
+				out <- i
+			}
+		}),
+		r.Job(name, 2),
+		job(func(in, out chan interface{}) {
+			for r := range in {
+				got = append(got, r.(string))
+			}
+		}),
+	)
+	return got
+}
+
+func TestRemoteJob(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+	}{
+		{"tcp", "tcp:127.0.0.1:0"},
+		{"unix", "unix:" + filepath.Join(t.TempDir(), "worker.sock")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := ListenWorker(tt.addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			go ServeWorker(l, map[string]job{"upper": upperJob})
+
+			addr := l.Addr().Network() + ":" + l.Addr().String()
+			r, err := DialWorkers(addr, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+
+			got := runRemote(r, "upper", "a", "b", 1, "c")
+			if want := []string{"A", "B", "1", "C"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("remote upper = %v, want %v", got, want)
+			}
+			if err := r.Err(); err != nil {
+				t.Errorf("Err() = %v", err)
+			}
+
+			runRemote(r, "nope", "a")
+			if err := r.Err(); err == nil || !strings.Contains(err.Error(), `unknown job "nope"`) {
+				t.Errorf("Err() = %v, want unknown job", err)
+			}
+		})
+	}
+}
+
+func TestRemoteWorkerCrash(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// connection is closed before reply to the first item is written
+	serveOne(l, func(conn net.Conn) map[string]job {
+		return map[string]job{"crash": job(func(in, out chan interface{}) {
+			for range in {
+				conn.Close()
+			}
+		})}
+	})
+
+	r, err := DialWorkers("tcp:" + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if got := runRemote(r, "crash", "a", "b", "c"); len(got) != 0 {
+		t.Errorf("remote crash = %v, want no results", got)
+	}
+	if r.Err() == nil {
+		t.Errorf("Err() = nil, want worker failure")
+	}
+}
+
+func TestRemoteTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	stall := make(chan struct{})
+	defer close(stall)
+	serveOne(l, func(net.Conn) map[string]job {
+		return map[string]job{"stall": job(func(in, out chan interface{}) {
+			for range in {
+				<-stall
+			}
+		})}
+	})
+
+	r, err := DialWorkers("tcp:" + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	r.Timeout = time.Second
+
+	var got []string
+	clk := NewFakeClock(time.Unix(0, 0))
+	done := goWithClock(clk, func() { got = runRemote(r, "stall", "a") })
+	blockUntil(t, clk, 1)
+	clk.Advance(time.Second)
+	<-done
+
+	if len(got) != 0 {
+		t.Errorf("remote stall = %v, want no results", got)
+	}
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "no reply in 1s") {
+		t.Errorf("Err() = %v, want timeout", err)
+	}
+}
+
+func TestRemoteSalt(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	srv := rpc.NewServer()
+	srv.Register(&Worker{jobs: map[string]job{"upper": upperJob}, salt: DataSignerSalt + "x"})
+	go srv.Accept(l)
+
+	_, err = DialWorkers("tcp:" + l.Addr().String())
+	if err == nil || !strings.Contains(err.Error(), "salt differs") {
+		t.Errorf("DialWorkers() error = %v, want salt mismatch", err)
+	}
+}
+
+func TestParseAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		network string
+		address string
+		ok      bool
+	}{
+		{"unix:/tmp/w.sock", "unix", "/tmp/w.sock", true},
+		{"tcp:127.0.0.1:9000", "tcp", "127.0.0.1:9000", true},
+		{"127.0.0.1:9000", "", "", false},
+		{"tcp:", "", "", false},
+		{"/tmp/w.sock", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			network, address, err := parseAddr(tt.addr)
+			if network != tt.network || address != tt.address || (err == nil) != tt.ok {
+				t.Errorf("parseAddr() = %v, %v, %v", network, address, err)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 var (
@@ -35,23 +36,62 @@ func singleHash(data string) string {
 	return strings.Join(dataSignerCrc32(data, dataSignerMd5(data)), "~")
 }
 
-func SingleHash(in, out chan interface{}) {
-	var tohash string
-	wg := sync.WaitGroup{}
-	for i := range in {
-		switch m := (i).(type) {
-		case int:
-			tohash = fmt.Sprintf("%d", m)
-		case string:
-			tohash = m
+// parallel calls f for every input item and its index, at most limit items are processed
+// concurrently (zero means no limit). results are sent in the input order
+func parallel(limit int, f func(int, interface{}) []interface{}) job {
+	return func(in, out chan interface{}) {
+		var sem chan struct{}
+		if limit > 0 {
+			sem = make(chan struct{}, limit)
 		}
-		wg.Add(1)
-		go func(str string) {
-			out <- singleHash(str)
-			wg.Done()
-		}(tohash)
+		wg := sync.WaitGroup{}
+		prev := make(chan struct{})
+		close(prev)
+		n := 0
+		for i := range in {
+			if sem != nil {
+				sem <- struct{}{}
+			}
+			next := make(chan struct{})
+			wg.Add(1)
+			go func(n int, i interface{}, prev, next chan struct{}) {
+				defer wg.Done()
+				rv := f(n, i)
+				<-prev
+				for _, r := range rv {
+					out <- r
+				}
+				close(next)
+				if sem != nil {
+					<-sem
+				}
+			}(n, i, prev, next)
+			prev = next
+			n++
+		}
+		wg.Wait()
 	}
-	wg.Wait()
+}
+
+// hashJob applies f to every int, string or envelope input item, see parallel
+func hashJob(name string, f func(string) string, limit int) job {
+	return parallel(limit, func(_ int, i interface{}) []interface{} {
+		tohash, ok := itemValue(i)
+		if !ok {
+			return nil
+		}
+		start := SignerClock.Now()
+		return withResults(i, name, start, f(tohash))
+	})
+}
+
+func SingleHash(in, out chan interface{}) {
+	hashJob("SingleHash", singleHash, 0)(in, out)
+}
+
+// SingleHashN is SingleHash limited to n concurrent items
+func SingleHashN(n int) job {
+	return hashJob("SingleHash", singleHash, n)
 }
 
 func multiHash(data string) string {
@@ -65,35 +105,58 @@ func multiHash(data string) string {
 }
 
 func MultiHash(in, out chan interface{}) {
-	var tohash string
-	wg := sync.WaitGroup{}
-	for i := range in {
-		switch m := (i).(type) {
-		case int:
-			tohash = fmt.Sprintf("%d", m)
-		case string:
-			tohash = m
-		}
-		wg.Add(1)
-		go func(str string) {
-			out <- multiHash(str)
-			wg.Done()
-		}(tohash)
+	hashJob("MultiHash", multiHash, 0)(in, out)
+}
+
+// MultiHashN is MultiHash limited to n concurrent items
+func MultiHashN(n int) job {
+	return hashJob("MultiHash", multiHash, n)
+}
+
+// combiner collects items to be sorted and joined
+type combiner struct {
+	data  []string
+	envs  []*Envelope
+	start time.Time
+}
+
+func (c *combiner) add(i interface{}) {
+	switch m := (i).(type) {
+	case string:
+	case *Envelope:
+		c.envs = append(c.envs, m)
+	default:
+		return
 	}
-	wg.Wait()
+	if len(c.data) == 0 {
+		c.start = SignerClock.Now()
+	}
+	v, _ := itemValue(i)
+	c.data = append(c.data, v)
 }
 
-func CombineResults(in, out chan interface{}) {
-	data := make([]string, 0)
+// flush returns joined result and resets collected items,
+// result is an envelope combining input ones if there were any
+func (c *combiner) flush() interface{} {
+	sort.Strings(c.data)
+	res := strings.Join(c.data, "_")
+	c.data = c.data[:0]
+	if len(c.envs) == 0 {
+		return res
+	}
+	e := newEnvelope(res)
+	e.Parents = c.envs
+	e.span("CombineResults", c.start, res)
+	c.envs = nil
+	return e
+}
 
+func CombineResults(in, out chan interface{}) {
+	c := combiner{}
 	for i := range in {
-		switch m := (i).(type) {
-		case string:
-			data = append(data, m)
-		}
+		c.add(i)
 	}
-	sort.Strings(data)
-	out <- fmt.Sprint(strings.Join(data, "_"))
+	out <- c.flush()
 }
 
 func ExecutePipeline(jobs ...job) {
@@ -112,15 +175,3 @@ func ExecutePipeline(jobs ...job) {
 	}
 	wg.Wait()
 }
-
-func main() {
-	in := make(chan interface{}, 1)
-	out := make(chan interface{}, 1)
-
-	in <- "1"
-
-	SingleHash(in, out)
-	MultiHash(out, in)
-	CombineResults(in, out)
-	fmt.Println((<-out).(string))
-}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 )
 
 func Test_singleHash(t *testing.T) {
@@ -18,7 +19,14 @@ func Test_singleHash(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := singleHash(tt.args.data); got != tt.want {
+			var got string
+			clk := NewFakeClock(time.Unix(0, 0))
+			done := goWithClock(clk, func() { got = singleHash(tt.args.data) })
+			// md5 and then two crc32 at once
+			if d, want := advanceUntilDone(t, clk, done), 1010*time.Millisecond; d != want {
+				t.Errorf("singleHash() took %s, want %s", d, want)
+			}
+			if got != tt.want {
 				t.Errorf("singleHash() = %v, want %v", got, tt.want)
 			}
 		})
@@ -39,7 +47,13 @@ func Test_multiHash(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := multiHash(tt.args.data); got != tt.want {
+			var got string
+			clk := NewFakeClock(time.Unix(0, 0))
+			done := goWithClock(clk, func() { got = multiHash(tt.args.data) })
+			if d := advanceUntilDone(t, clk, done); d != time.Second {
+				t.Errorf("multiHash() took %s, want 1s", d)
+			}
+			if got != tt.want {
 				t.Errorf("multiHash() = %v, want %v", got, tt.want)
 			}
 		})
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Span is a single stage passed by an item
+type Span struct {
+	Stage  string
+	Start  time.Time
+	End    time.Time
+	Output string
+}
+
+// Envelope carries an item through the pipeline in trace mode,
+// stages work with Value and append their spans
+type Envelope struct {
+	ID    uint64
+	Value string
+	Spans []Span
+	// envelopes combined into this one
+	Parents []*Envelope
+}
+
+var lastEnvelopeID uint64
+
+func newEnvelope(value string) *Envelope {
+	return &Envelope{ID: atomic.AddUint64(&lastEnvelopeID, 1), Value: value}
+}
+
+func (e *Envelope) clone() *Envelope {
+	rv := *e
+	rv.ID = atomic.AddUint64(&lastEnvelopeID, 1)
+	rv.Spans = append([]Span(nil), e.Spans...)
+	return &rv
+}
+
+func (e *Envelope) span(stage string, start time.Time, output string) {
+	e.Spans = append(e.Spans, Span{Stage: stage, Start: start, End: SignerClock.Now(), Output: output})
+	e.Value = output
+}
+
+// itemValue returns string value of int, string or envelope item
+func itemValue(i interface{}) (string, bool) {
+	switch m := (i).(type) {
+	case int:
+		return fmt.Sprintf("%d", m), true
+	case string:
+		return m, true
+	case *Envelope:
+		return m.Value, true
+	}
+	return "", false
+}
+
+// withResults turns results of stage computed for item i into items for the next stage,
+// envelope gets a new span and is cloned if there are several results
+func withResults(i interface{}, stage string, start time.Time, res ...string) []interface{} {
+	rv := make([]interface{}, len(res))
+	env, ok := i.(*Envelope)
+	if !ok {
+		for k := range res {
+			rv[k] = res[k]
+		}
+		return rv
+	}
+
+	envs := []*Envelope{env}
+	for len(envs) < len(res) {
+		envs = append(envs, env.clone())
+	}
+	for k := range res {
+		envs[k].span(stage, start, res[k])
+		rv[k] = envs[k]
+	}
+	return rv
+}
+
+// Tracer wraps pipeline input into envelopes and collects them at the end
+type Tracer struct {
+	start   time.Time
+	mu      sync.Mutex
+	results []*Envelope
+}
+
+func NewTracer() *Tracer {
+	return &Tracer{start: SignerClock.Now()}
+}
+
+// Wrap returns job putting every int or string item into a new envelope
+func (t *Tracer) Wrap() job {
+	return func(in, out chan interface{}) {
+		for i := range in {
+			switch i.(type) {
+			case int, string:
+			default:
+				out <- i
+				continue
+			}
+			v, _ := itemValue(i)
+			e := newEnvelope(v)
+			e.span("input", SignerClock.Now(), v)
+			out <- e
+		}
+	}
+}
+
+// Unwrap returns job taking values out of envelopes, envelopes are kept for Dump
+func (t *Tracer) Unwrap() job {
+	return func(in, out chan interface{}) {
+		for i := range in {
+			e, ok := i.(*Envelope)
+			if !ok {
+				out <- i
+				continue
+			}
+			t.mu.Lock()
+			t.results = append(t.results, e)
+			t.mu.Unlock()
+			out <- e.Value
+		}
+	}
+}
+
+// Results returns envelopes collected by Unwrap
+func (t *Tracer) Results() []*Envelope {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*Envelope(nil), t.results...)
+}
+
+// Dump writes lineage of every result, spans times are relative to tracer creation
+func (t *Tracer) Dump(w io.Writer) error {
+	for _, e := range t.Results() {
+		if _, err := fmt.Fprintf(w, "result #%d %s\n", e.ID, e.Value); err != nil {
+			return err
+		}
+		if err := t.dump(w, e, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Tracer) dump(w io.Writer, e *Envelope, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	for _, s := range e.Spans {
+		_, err := fmt.Fprintf(w, "%s#%d %s [%s +%s] %s\n", indent, e.ID, s.Stage,
+			s.Start.Sub(t.start), s.End.Sub(s.Start), s.Output)
+		if err != nil {
+			return err
+		}
+	}
+	for _, p := range e.Parents {
+		if err := t.dump(w, p, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracer(t *testing.T) {
+	var (
+		tracer *Tracer
+		result string
+	)
+	clk := NewFakeClock(time.Unix(0, 0))
+	done := goWithClock(clk, func() {
+		tracer = NewTracer()
+		ExecutePipeline(
+			job(func(in, out chan interface{}) {
+				out <- 0
+				out <- "1"
+			}),
+			tracer.Wrap(),
+			job(SingleHash),
+			job(MultiHash),
+			job(CombineResults),
+			tracer.Unwrap(),
+			job(func(in, out chan interface{}) {
+				result = (<-in).(string)
+			}),
+		)
+	})
+	advanceUntilDone(t, clk, done)
+
+	want := "29568666068035183841425683795340791879727309630931025356555_4958044192186797981418233587017209679042592862002427381542"
+	if result != want {
+		t.Fatalf("result = %v, want %v", result, want)
+	}
+
+	results := tracer.Results()
+	if len(results) != 1 || len(results[0].Parents) != 2 {
+		t.Fatalf("Results() = %v, want one result with two parents", results)
+	}
+
+	lineage := map[string][]string{}
+	for _, p := range results[0].Parents {
+		stages := []string{}
+		outputs := []string{}
+		for _, s := range p.Spans {
+			stages = append(stages, s.Stage)
+			outputs = append(outputs, s.Output)
+			if s.End.Before(s.Start) {
+				t.Errorf("span %s of #%d ends before start", s.Stage, p.ID)
+			}
+		}
+		if want := []string{"input", "SingleHash", "MultiHash"}; !reflect.DeepEqual(stages, want) {
+			t.Errorf("stages of #%d = %v, want %v", p.ID, stages, want)
+		}
+		lineage[outputs[0]] = outputs
+	}
+	wantLineage := map[string][]string{
+		"0": {"0", "4108050209~502633748", "29568666068035183841425683795340791879727309630931025356555"},
+		"1": {"1", "2212294583~709660146", "4958044192186797981418233587017209679042592862002427381542"},
+	}
+	if !reflect.DeepEqual(lineage, wantLineage) {
+		t.Errorf("lineage = %v, want %v", lineage, wantLineage)
+	}
+
+	out := new(bytes.Buffer)
+	if err := tracer.Dump(out); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"result #", "CombineResults", "SingleHash", "2212294583~709660146"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("Dump() = %v, want it to contain %q", out.String(), s)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"time"
+)
+
+// CombineResultsWindow returns CombineResults that doesnt wait for input to be closed
+// and flushes collected results every size items or every period, whichever comes first.
+// zero size or period disables corresponding trigger. partial window is flushed on close
+func CombineResultsWindow(size int, period time.Duration) job {
+	return func(in, out chan interface{}) {
+		c := combiner{}
+		flush := func() {
+			if len(c.data) == 0 {
+				return
+			}
+			out <- c.flush()
+		}
+
+		var tick <-chan time.Time
+		if period > 0 {
+			tick = SignerClock.After(period)
+		}
+
+		for {
+			select {
+			case i, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				c.add(i)
+				if size > 0 && len(c.data) >= size {
+					flush()
+				}
+			case <-tick:
+				flush()
+				tick = SignerClock.After(period)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCombineResultsWindow(t *testing.T) {
+	tests := []struct {
+		name   string
+		size   int
+		period time.Duration
+		input  []string
+		want   []string
+	}{
+		{"size", 2, 0, []string{"b", "a", "d", "c"}, []string{"a_b", "c_d"}},
+		{"partial", 3, 0, []string{"c", "b", "a", "e", "d"}, []string{"a_b_c", "d_e"}},
+		{"close", 0, 0, []string{"b", "c", "a"}, []string{"a_b_c"}},
+		{"empty", 2, 0, []string{}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			ExecutePipeline(
+				job(func(in, out chan interface{}) {
+					for _, s := range tt.input {
+						out <- s
+					}
+				}),
+				CombineResultsWindow(tt.size, tt.period),
+				job(func(in, out chan interface{}) {
+					for r := range in {
+						got = append(got, r.(string))
+					}
+				}),
+			)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CombineResultsWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombineResultsWindowPeriod(t *testing.T) {
+	got := []string{}
+	clk := NewFakeClock(time.Unix(0, 0))
+	done := goWithClock(clk, func() {
+		ExecutePipeline(
+			job(func(in, out chan interface{}) {
+				out <- "b"
+				out <- "a"
+				SignerClock.Sleep(100 * time.Millisecond)
+				out <- "c"
+			}),
+			CombineResultsWindow(0, 50*time.Millisecond),
+			job(func(in, out chan interface{}) {
+				for r := range in {
+					got = append(got, r.(string))
+				}
+			}),
+		)
+	})
+	// window period and input sleep, a and b are received before the sleep
+	blockUntil(t, clk, 2)
+	clk.Advance(50 * time.Millisecond)
+	// the next period
+	blockUntil(t, clk, 2)
+	clk.Advance(50 * time.Millisecond)
+	<-done
+
+	want := []string{"a_b", "c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CombineResultsWindow() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// DefaultThresholds are allowed relative regressions of new benchmarks,
+// 0.25 means 25% more than in baseline is still fine
+var DefaultThresholds = map[string]float64{
+	"ns/op":     0.25,
+	"B/op":      0.10,
+	"allocs/op": 0.10,
+}
+
+// Baseline is stored benchmark means with regression thresholds per benchmark
+type Baseline struct {
+	Benchmarks map[string]*BaselineEntry `json:"benchmarks"`
+}
+
+type BaselineEntry struct {
+	Metrics    map[string]float64 `json:"metrics"`
+	Thresholds map[string]float64 `json:"thresholds"`
+}
+
+// Regression is a metric that got worse than its threshold allows
+type Regression struct {
+	Name      string
+	Unit      string
+	Base      float64
+	Got       float64
+	Threshold float64
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s %s: %s -> %s (%s, threshold %+.0f%%)", r.Name, r.Unit,
+		formatValue(r.Base), formatValue(r.Got), formatDelta(r.Got, r.Base), 100*r.Threshold)
+}
+
+func LoadBaseline(fp string) (*Baseline, error) {
+	data, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	b := &Baseline{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("%s: %w", fp, err)
+	}
+	if b.Benchmarks == nil {
+		b.Benchmarks = map[string]*BaselineEntry{}
+	}
+	return b, nil
+}
+
+func (b *Baseline) Save(fp string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fp, append(data, '\n'), 0644)
+}
+
+// Update stores means of samples, thresholds of known benchmarks are kept
+func (b *Baseline) Update(samples []*Sample) {
+	if b.Benchmarks == nil {
+		b.Benchmarks = map[string]*BaselineEntry{}
+	}
+	for _, s := range samples {
+		e, ok := b.Benchmarks[s.Name]
+		if !ok {
+			e = &BaselineEntry{Thresholds: map[string]float64{}}
+			for unit, t := range DefaultThresholds {
+				e.Thresholds[unit] = t
+			}
+			b.Benchmarks[s.Name] = e
+		}
+		e.Metrics = map[string]float64{}
+		for _, unit := range Units {
+			if v, ok := s.Values[unit]; ok {
+				e.Metrics[unit] = Summarize(v).Mean
+			}
+		}
+	}
+}
+
+// Check compares means of samples with baseline and writes a diff table.
+// benchmarks absent from either side are listed but are not regressions
+func (b *Baseline) Check(w io.Writer, samples []*Sample) ([]Regression, error) {
+	rv := []Regression{}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "benchmark\tunit\tbaseline\tcurrent\tdelta\tthreshold\tstatus\n")
+
+	seen := map[string]bool{}
+	for _, s := range samples {
+		seen[s.Name] = true
+		e, ok := b.Benchmarks[s.Name]
+		if !ok {
+			fmt.Fprintf(tw, "%s\t\t\t\t\t\tnew\n", s.Name)
+			continue
+		}
+		for _, unit := range Units {
+			v, ok := s.Values[unit]
+			base, inBase := e.Metrics[unit]
+			if !ok || !inBase {
+				continue
+			}
+			got := Summarize(v).Mean
+			t, ok := e.Thresholds[unit]
+			if !ok {
+				t = DefaultThresholds[unit]
+			}
+			status := "ok"
+			// small epsilon keeps equal values of zero threshold from failing
+			if got > base*(1+t)+1e-9 {
+				status = "REGRESSION"
+				rv = append(rv, Regression{Name: s.Name, Unit: unit, Base: base, Got: got, Threshold: t})
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%+.0f%%\t%s\n", s.Name, unit,
+				formatValue(base), formatValue(got), formatDelta(got, base), 100*t, status)
+		}
+	}
+
+	missing := []string{}
+	for name := range b.Benchmarks {
+		if !seen[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		fmt.Fprintf(tw, "%s\t\t\t\t\t\tmissing\n", name)
+	}
+
+	return rv, tw.Flush()
+}
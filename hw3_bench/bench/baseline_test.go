@@ -0,0 +1,60 @@
+package bench
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBaseline(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "baseline.json")
+
+	b := &Baseline{}
+	b.Update([]*Sample{
+		{"BenchmarkSlow", map[string][]float64{"ns/op": {90, 110}, "B/op": {1000, 1000}, "allocs/op": {10, 10}}},
+		{"BenchmarkFast", map[string][]float64{"ns/op": {10}, "B/op": {0}, "allocs/op": {0}}},
+		{"BenchmarkGone", map[string][]float64{"ns/op": {1}}},
+	})
+	b.Benchmarks["BenchmarkSlow"].Thresholds["ns/op"] = 0.5
+	if err := b.Save(fp); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := LoadBaseline(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Benchmarks["BenchmarkSlow"].Metrics["ns/op"]; got != 100 {
+		t.Errorf("stored ns/op = %v, want mean 100", got)
+	}
+
+	out := new(bytes.Buffer)
+	regs, err := b.Check(out, []*Sample{
+		{"BenchmarkSlow", map[string][]float64{"ns/op": {140}, "B/op": {1200}, "allocs/op": {10}}},
+		{"BenchmarkFast", map[string][]float64{"ns/op": {10}, "B/op": {0}, "allocs/op": {1}}},
+		{"BenchmarkNew", map[string][]float64{"ns/op": {5}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Regression{
+		{"BenchmarkSlow", "B/op", 1000, 1200, 0.1},
+		{"BenchmarkFast", "allocs/op", 0, 1, 0.1},
+	}
+	if !reflect.DeepEqual(regs, want) {
+		t.Errorf("Check() = %v, want %v", regs, want)
+	}
+	for _, s := range []string{"BenchmarkNew", "new", "BenchmarkGone", "missing", "REGRESSION", "+40.00%"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("Check() table = %s, want it to contain %q", out, s)
+		}
+	}
+
+	// update keeps tuned thresholds
+	b.Update([]*Sample{{"BenchmarkSlow", map[string][]float64{"ns/op": {140}}}})
+	if got := b.Benchmarks["BenchmarkSlow"].Thresholds["ns/op"]; got != 0.5 {
+		t.Errorf("threshold after Update() = %v, want 0.5", got)
+	}
+}
@@ -0,0 +1,109 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"text/tabwriter"
+)
+
+// Units are the metrics go test -benchmem reports
+var Units = []string{"ns/op", "B/op", "allocs/op"}
+
+// Sample is all the runs of a single benchmark
+type Sample struct {
+	Name   string
+	Values map[string][]float64
+}
+
+// Group collects results into samples by name in order of first appearance
+func Group(results []Result) []*Sample {
+	rv := []*Sample{}
+	byName := map[string]*Sample{}
+	for _, r := range results {
+		s, ok := byName[r.Name]
+		if !ok {
+			s = &Sample{Name: r.Name, Values: map[string][]float64{}}
+			byName[r.Name] = s
+			rv = append(rv, s)
+		}
+		for unit, v := range r.Metrics {
+			s.Values[unit] = append(s.Values[unit], v)
+		}
+	}
+	return rv
+}
+
+// Compare writes a table per unit comparing every sample with the base one.
+// difference is marked with * when Welch's t-test p-value is below alpha
+func Compare(w io.Writer, samples []*Sample, base string, alpha float64) error {
+	var baseSample *Sample
+	for _, s := range samples {
+		if s.Name == base {
+			baseSample = s
+		}
+	}
+	if baseSample == nil {
+		return fmt.Errorf("no base benchmark %s in results", base)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, unit := range Units {
+		b := Summarize(baseSample.Values[unit])
+		fmt.Fprintf(tw, "%s\tmean\t± 95%% CI\tn\tdelta\tp\n", unit)
+		for _, s := range samples {
+			v, ok := s.Values[unit]
+			if !ok {
+				continue
+			}
+			sum := Summarize(v)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t", s.Name, formatValue(sum.Mean), formatCI(sum), sum.N)
+			if s == baseSample {
+				fmt.Fprintf(tw, "base\n")
+				continue
+			}
+			p := WelchTest(sum, b)
+			mark := ""
+			if p < alpha {
+				mark = " *"
+			}
+			fmt.Fprintf(tw, "%s\t%s%s\n", formatDelta(sum.Mean, b.Mean), formatP(p), mark)
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func formatValue(v float64) string {
+	if v >= 100 || v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+func formatCI(s Summary) string {
+	if math.IsNaN(s.CI) {
+		return "?"
+	}
+	if s.Mean == 0 {
+		return "± " + formatValue(s.CI)
+	}
+	return fmt.Sprintf("± %.1f%%", 100*s.CI/s.Mean)
+}
+
+func formatDelta(v, base float64) string {
+	if base == 0 {
+		if v == 0 {
+			return "~"
+		}
+		return "+inf"
+	}
+	return fmt.Sprintf("%+.2f%%", 100*(v-base)/base)
+}
+
+func formatP(p float64) string {
+	if math.IsNaN(p) {
+		return "?"
+	}
+	return fmt.Sprintf("%.3f", p)
+}
@@ -0,0 +1,73 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Result is a single line of go test -bench output
+type Result struct {
+	// name without -GOMAXPROCS suffix
+	Name    string
+	N       int
+	Metrics map[string]float64
+}
+
+// Parse reads benchmark results from go test -bench output,
+// other lines are ignored
+func Parse(r io.Reader) ([]Result, error) {
+	rv := []Result{}
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		res, ok, err := parseLine(sc.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if ok {
+			rv = append(rv, res)
+		}
+	}
+	return rv, sc.Err()
+}
+
+// parseLine parses line like
+//
+//	BenchmarkFast-8   	     928	   1404655 ns/op	  623063 B/op	     323 allocs/op
+func parseLine(line string) (Result, bool, error) {
+	f := strings.Fields(line)
+	if len(f) < 4 || !strings.HasPrefix(f[0], "Benchmark") {
+		return Result{}, false, nil
+	}
+	n, err := strconv.Atoi(f[1])
+	if err != nil {
+		// benchmark name printed by -v or a log line
+		return Result{}, false, nil
+	}
+	if len(f)%2 != 0 {
+		return Result{}, false, fmt.Errorf("odd number of value and unit fields in %q", line)
+	}
+
+	res := Result{Name: trimProcs(f[0]), N: n, Metrics: map[string]float64{}}
+	for i := 2; i < len(f); i += 2 {
+		v, err := strconv.ParseFloat(f[i], 64)
+		if err != nil {
+			return Result{}, false, fmt.Errorf("bad %s value %q", f[i+1], f[i])
+		}
+		res.Metrics[f[i+1]] = v
+	}
+	return res, true, nil
+}
+
+func trimProcs(name string) string {
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return name
+	}
+	if _, err := strconv.Atoi(name[i+1:]); err != nil {
+		return name
+	}
+	return name[:i]
+}
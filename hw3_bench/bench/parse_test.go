@@ -0,0 +1,62 @@
+package bench
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	out := `goos: linux
+goarch: amd64
+pkg: localhost/coursera/hw3_bench
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkSlow-8        	      49	  27488024 ns/op	17894849 B/op	  177389 allocs/op
+BenchmarkFast          	     928	   1404655.5 ns/op	  623063 B/op	     323 allocs/op
+BenchmarkFast-v2-16    	     928	   1404655 ns/op	  12.50 MB/s
+BenchmarkVerbose
+    main_test.go:10: log line
+PASS
+ok  	localhost/coursera/hw3_bench	5.811s
+`
+	got, err := Parse(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []Result{
+		{"BenchmarkSlow", 49, map[string]float64{"ns/op": 27488024, "B/op": 17894849, "allocs/op": 177389}},
+		{"BenchmarkFast", 928, map[string]float64{"ns/op": 1404655.5, "B/op": 623063, "allocs/op": 323}},
+		{"BenchmarkFast-v2", 928, map[string]float64{"ns/op": 1404655, "MB/s": 12.5}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %v, want %v", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"BenchmarkFast 10 100 ns/op 5",
+		"BenchmarkFast 10 fast ns/op",
+	}
+	for _, tt := range tests {
+		if _, err := Parse(strings.NewReader(tt)); err == nil {
+			t.Errorf("Parse(%q) expected error", tt)
+		}
+	}
+}
+
+func TestGroup(t *testing.T) {
+	results := []Result{
+		{"B", 1, map[string]float64{"ns/op": 1}},
+		{"A", 1, map[string]float64{"ns/op": 2}},
+		{"B", 1, map[string]float64{"ns/op": 3, "B/op": 4}},
+	}
+	got := Group(results)
+	want := []*Sample{
+		{"B", map[string][]float64{"ns/op": {1, 3}, "B/op": {4}}},
+		{"A", map[string][]float64{"ns/op": {2}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Group() = %v, want %v", got, want)
+	}
+}
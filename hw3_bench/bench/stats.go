@@ -0,0 +1,137 @@
+package bench
+
+import (
+	"math"
+)
+
+// Summary describes a sample of measurements
+type Summary struct {
+	N      int
+	Mean   float64
+	Stddev float64
+	// half width of 95% confidence interval of the mean, NaN for a single value
+	CI float64
+}
+
+func Summarize(xs []float64) Summary {
+	s := Summary{N: len(xs), CI: math.NaN()}
+	if s.N == 0 {
+		s.Mean = math.NaN()
+		return s
+	}
+	for _, x := range xs {
+		s.Mean += x
+	}
+	s.Mean /= float64(s.N)
+	if s.N < 2 {
+		return s
+	}
+	for _, x := range xs {
+		s.Stddev += (x - s.Mean) * (x - s.Mean)
+	}
+	s.Stddev = math.Sqrt(s.Stddev / float64(s.N-1))
+	s.CI = TQuantile(0.975, float64(s.N-1)) * s.Stddev / math.Sqrt(float64(s.N))
+	return s
+}
+
+// WelchTest returns two sided p-value of Welch's t-test for equal means
+func WelchTest(a, b Summary) float64 {
+	if a.N < 2 || b.N < 2 {
+		return math.NaN()
+	}
+	va := a.Stddev * a.Stddev / float64(a.N)
+	vb := b.Stddev * b.Stddev / float64(b.N)
+	if va+vb == 0 {
+		if a.Mean == b.Mean {
+			return 1
+		}
+		return 0
+	}
+	t := (a.Mean - b.Mean) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(a.N-1) + vb*vb/float64(b.N-1))
+	return 2 * (1 - TCDF(math.Abs(t), df))
+}
+
+// TCDF is the cumulative distribution function of Student's t-distribution
+func TCDF(t, df float64) float64 {
+	p := 0.5 * incompleteBeta(df/(df+t*t), df/2, 0.5)
+	if t > 0 {
+		return 1 - p
+	}
+	return p
+}
+
+// TQuantile is the inverse of TCDF
+func TQuantile(p, df float64) float64 {
+	if p == 0.5 {
+		return 0
+	}
+	if p < 0.5 {
+		return -TQuantile(1-p, df)
+	}
+	lo, hi := 0.0, 1.0
+	for TCDF(hi, df) < p {
+		lo, hi = hi, hi*2
+	}
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if TCDF(mid, df) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// incompleteBeta is the regularized incomplete beta function I_x(a, b)
+func incompleteBeta(x, a, b float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	// continued fraction converges fast below the mean only
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaFraction(1-x, b, a)/b
+	}
+	return front * betaFraction(x, a, b) / a
+}
+
+// betaFraction evaluates continued fraction for incompleteBeta by modified Lentz's method
+func betaFraction(x, a, b float64) float64 {
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	f := d
+	for m := 1; m <= 300; m++ {
+		fm := float64(m)
+		for _, num := range []float64{
+			fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm)),
+			-(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1)),
+		} {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			f *= c * d
+		}
+		if math.Abs(c*d-1) < 1e-15 {
+			break
+		}
+	}
+	return f
+}
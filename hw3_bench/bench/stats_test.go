@@ -0,0 +1,91 @@
+package bench
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func near(a, b, eps float64) bool {
+	return math.Abs(a-b) <= eps
+}
+
+func TestTQuantile(t *testing.T) {
+	tests := []struct {
+		p, df, want float64
+	}{
+		{0.975, 1, 12.7062},
+		{0.975, 4, 2.7764},
+		{0.975, 9, 2.2622},
+		{0.975, 30, 2.0423},
+		{0.95, 10, 1.8125},
+		{0.025, 4, -2.7764},
+		{0.5, 3, 0},
+	}
+	for _, tt := range tests {
+		if got := TQuantile(tt.p, tt.df); !near(got, tt.want, 1e-4) {
+			t.Errorf("TQuantile(%v, %v) = %v, want %v", tt.p, tt.df, got, tt.want)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if s.N != 8 || s.Mean != 5 || !near(s.Stddev, 2.1381, 1e-4) || !near(s.CI, 1.7875, 1e-4) {
+		t.Errorf("Summarize() = %+v", s)
+	}
+	if s := Summarize([]float64{3}); s.Mean != 3 || !math.IsNaN(s.CI) {
+		t.Errorf("Summarize() of one value = %+v", s)
+	}
+}
+
+func TestWelchTest(t *testing.T) {
+	a := Summarize([]float64{27.5, 21.0, 19.0, 23.6, 17.0, 17.9, 16.9, 20.1, 21.9, 22.6, 23.1, 19.6, 19.0, 21.7, 21.4})
+	b := Summarize([]float64{27.1, 22.0, 20.8, 23.4, 23.4, 23.5, 25.8, 22.0, 24.8, 20.2, 21.9, 22.1, 22.9, 20.5, 24.4})
+	// t = -2.4554, df = 24.99
+	if p := WelchTest(a, b); !near(p, 0.021378, 1e-6) {
+		t.Errorf("WelchTest() = %v, want 0.021378", p)
+	}
+	if p := WelchTest(a, a); !near(p, 1, 1e-9) {
+		t.Errorf("WelchTest() of the same samples = %v, want 1", p)
+	}
+	c := Summarize([]float64{1, 1, 1})
+	d := Summarize([]float64{2, 2, 2})
+	if p := WelchTest(c, d); p != 0 {
+		t.Errorf("WelchTest() of constant samples = %v, want 0", p)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	samples := []*Sample{
+		{"BenchmarkSlow", map[string][]float64{"ns/op": {100, 102, 98}, "B/op": {10, 10, 10}, "allocs/op": {5, 5, 5}}},
+		{"BenchmarkFast", map[string][]float64{"ns/op": {50, 51, 49}, "B/op": {10, 10, 10}, "allocs/op": {1, 1, 1}}},
+	}
+	out := new(bytes.Buffer)
+	if err := Compare(out, samples, "BenchmarkSlow", 0.05); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(out.String(), "\n")
+	for _, tt := range []struct {
+		line int
+		want []string
+	}{
+		{1, []string{"BenchmarkSlow", "100", "± 5.0%", "base"}},
+		{2, []string{"BenchmarkFast", "50", "-50.00%", "*"}},
+		{6, []string{"BenchmarkFast", "10", "+0.00%", "1.000"}},
+	} {
+		for _, w := range tt.want {
+			if !strings.Contains(lines[tt.line], w) {
+				t.Errorf("Compare() line %d = %q, want it to contain %q", tt.line, lines[tt.line], w)
+			}
+		}
+	}
+	if strings.Contains(lines[6], "*") {
+		t.Errorf("Compare() marks equal B/op as different: %q", lines[6])
+	}
+
+	if err := Compare(out, samples, "BenchmarkNone", 0.05); err == nil {
+		t.Errorf("Compare() expected error for unknown base")
+	}
+}
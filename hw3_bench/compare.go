@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"localhost/coursera/hw3_bench/bench"
+)
+
+// runBenchmarks runs go test benchmarks of current package count times
+func runBenchmarks(pattern string, count int) ([]bench.Result, error) {
+	cmd := exec.Command("go", "test", "-run", "^$", "-bench", pattern, "-benchmem", "-count", strconv.Itoa(count))
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go test: %w\n%s", err, out)
+	}
+	return bench.Parse(bytes.NewReader(out))
+}
+
+// readBenchmarks parses saved go test output, - is stdin
+func readBenchmarks(fp string) ([]bench.Result, error) {
+	var r io.Reader = os.Stdin
+	if fp != "-" {
+		f, err := os.Open(fp)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return bench.Parse(r)
+}
+
+// benchResults reads saved benchmarks from in if it is set or runs them
+func benchResults(in, pattern string, count int) ([]*bench.Sample, error) {
+	var (
+		results []bench.Result
+		err     error
+	)
+	if in != "" {
+		results, err = readBenchmarks(in)
+	} else {
+		results, err = runBenchmarks(pattern, count)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	samples := bench.Group(results)
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no benchmark results")
+	}
+	return samples, nil
+}
+
+// compareCmd compares benchmarks statistically
+func compareCmd(args []string) error {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	pattern := fs.String("bench", ".", "benchmarks to run")
+	count := fs.Int("count", 10, "number of runs of every benchmark")
+	in := fs.String("in", "", "read go test -bench output from file (- is stdin) instead of running benchmarks")
+	base := fs.String("base", "", "benchmark others are compared with, the first one by default")
+	alpha := fs.Float64("alpha", 0.05, "significance level of differences")
+	fs.Parse(args)
+
+	samples, err := benchResults(*in, *pattern, *count)
+	if err != nil {
+		return err
+	}
+	if *base == "" {
+		*base = samples[0].Name
+	}
+	return bench.Compare(os.Stdout, samples, *base, *alpha)
+}
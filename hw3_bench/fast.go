@@ -1,15 +1,10 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
 	"io"
 	"localhost/coursera/hw3_bench/fast"
 	"os"
-	"os/exec"
-	"strconv"
-	"strings"
 )
 
 func FastSearch(out io.Writer) {
@@ -21,6 +16,29 @@ func FastSearch(out io.Writer) {
 	fast.FastSearch(out, data)
 }
 
+// MmapSearch is FastSearch scanning mapped file instead of reading it into memory
+func MmapSearch(out io.Writer) {
+	if err := mmapSearch(out, filePath); err != nil {
+		panic(err)
+	}
+}
+
+// mmapSearch searches file fp in place, file which cant be mapped is streamed
+func mmapSearch(out io.Writer, fp string) error {
+	file, err := os.Open(fp)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := mmap(file)
+	if err != nil {
+		return fast.SearchReader(out, file, fast.Options{})
+	}
+	defer munmap(data)
+	return fast.Search(out, data, fast.Options{})
+}
+
 func FastSearchDefault(out io.Writer) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -30,44 +48,92 @@ func FastSearchDefault(out io.Writer) {
 	fast.FastSearchDefault(out, data)
 }
 
-type benchResultsValues struct {
-	name   string
-	ops    uint32
-	bytes  uint32
-	allocs uint32
+// ParallelSearch parses users file on all CPUs
+func ParallelSearch(out io.Writer) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := fast.SearchParallel(out, data, fast.Options{}, 0); err != nil {
+		panic(err)
+	}
 }
 
-func main() {
-	out, err := exec.Command("go", "test", "-bench", ".", "-benchmem").Output()
+// StreamSearch reads users file line by line instead of loading it whole
+func StreamSearch(out io.Writer) {
+	file, err := os.Open(filePath)
 	if err != nil {
 		panic(err)
 	}
+	defer file.Close()
 
-	benchs := []benchResultsValues{}
-
-	reader := bufio.NewReader(bytes.NewReader(out))
-	for {
-		s, err := reader.ReadString('\n')
-		if err == io.EOF {
-			break
-		}
-		if strings.Contains(s, "Benchmark") {
-			ss := strings.Fields(s)
-			ops, _ := strconv.Atoi(ss[2])
-			bytes, _ := strconv.Atoi(ss[4])
-			allocs, _ := strconv.Atoi(ss[6])
-			bench := benchResultsValues{
-				name:   ss[0],
-				ops:    uint32(ops),
-				bytes:  uint32(bytes),
-				allocs: uint32(allocs),
-			}
-			benchs = append(benchs, bench)
-		}
+	if err := fast.SearchReader(out, file, fast.Options{}); err != nil {
+		panic(err)
 	}
+}
+
+// SearchFile searches users file fp, unlike the functions above it returns errors
+func SearchFile(out io.Writer, fp string, opts fast.Options) error {
+	file, err := os.Open(fp)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return fast.SearchReader(out, file, opts)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: %s [command] [flags]
 
-	fmt.Printf("%s vs %s\n", benchs[0].name, benchs[1].name)
-	fmt.Printf("\tns: \t%f\n", float32(benchs[0].ops)/float32(benchs[1].ops))
-	fmt.Printf("\tmem: \t%f\n", float32(benchs[0].bytes)/float32(benchs[1].bytes))
-	fmt.Printf("\talloc: \t%f\n", float32(benchs[0].allocs)/float32(benchs[1].allocs))
+commands:
+  compare   run benchmarks several times and compare them (default), see compare -h
+  search    search users file, see search -h
+  gen       generate synthetic users file, see gen -h
+  report    print browsers statistics of users file, see report -h
+  baseline  run benchmarks and store them in bench_baseline.json, see baseline -h
+  check     run benchmarks and fail on regressions against the baseline, see check -h
+  index     build browsers index of users file, see index -h
+  query     search users file using its index, see query -h
+  serve     serve searches over http, see serve -h
+`, os.Args[0])
+}
+
+func main() {
+	cmd, args := "compare", os.Args[1:]
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	var err error
+	switch cmd {
+	case "compare":
+		err = compareCmd(args)
+	case "search":
+		err = searchCmd(args)
+	case "gen":
+		err = genCmd(args)
+	case "report":
+		err = reportCmd(args)
+	case "baseline":
+		err = baselineCmd(args)
+	case "check":
+		err = checkCmd(args)
+	case "index":
+		err = indexCmd(args)
+	case "query":
+		err = queryCmd(args)
+	case "serve":
+		err = serveCmd(args)
+	case "-h", "-help", "--help", "help":
+		usage()
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,445 @@
+package fast
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// fieldMask is a set of User fields, bit n stands for field n
+type fieldMask uint8
+
+const allFields fieldMask = 1<<(fieldPhone+1) - 1
+
+func (m fieldMask) has(f field) bool {
+	return m&(1<<f) != 0
+}
+
+var fieldNames = [...][]byte{
+	fieldBrowsers: []byte("browsers"),
+	fieldCompany:  []byte("company"),
+	fieldCountry:  []byte("country"),
+	fieldEmail:    []byte("email"),
+	fieldJob:      []byte("job"),
+	fieldName:     []byte("name"),
+	fieldPhone:    []byte("phone"),
+}
+
+// Record is a User with fields pointing into the line it was extracted from,
+// values with escapes point into a buffer of the record instead.
+// fields are valid until the next Extract call
+type Record struct {
+	Browsers [][]byte
+	Company  []byte
+	Country  []byte
+	Email    []byte
+	Job      []byte
+	Name     []byte
+	Phone    []byte
+
+	scratch []byte
+}
+
+func (r *Record) field(f field) *[]byte {
+	switch f {
+	case fieldCompany:
+		return &r.Company
+	case fieldCountry:
+		return &r.Country
+	case fieldEmail:
+		return &r.Email
+	case fieldJob:
+		return &r.Job
+	case fieldName:
+		return &r.Name
+	case fieldPhone:
+		return &r.Phone
+	}
+	return nil
+}
+
+var errSyntax = errors.New("invalid json")
+
+type extractor struct {
+	data []byte
+	pos  int
+	rec  *Record
+}
+
+func (e *extractor) errorf(msg string) error {
+	return fmt.Errorf("%w: %s at %d", errSyntax, msg, e.pos)
+}
+
+// Extract fills the fields of want set from JSON object line, the rest
+// are skipped. nothing is allocated once record buffers are grown enough.
+// it agrees with encoding/json on every line encoding/json decodes into User
+func (r *Record) Extract(line []byte, want fieldMask) error {
+	r.Browsers = r.Browsers[:0]
+	r.Company, r.Country, r.Email, r.Job, r.Name, r.Phone = nil, nil, nil, nil, nil, nil
+	r.scratch = r.scratch[:0]
+
+	e := extractor{data: line, rec: r}
+	e.ws()
+	if e.literal("null") {
+		return e.end()
+	}
+	if !e.consume('{') {
+		return e.errorf("expected object")
+	}
+	e.ws()
+	if e.consume('}') {
+		return e.end()
+	}
+	for {
+		e.ws()
+		if e.peek() != '"' {
+			return e.errorf("expected key")
+		}
+		key, err := e.string()
+		if err != nil {
+			return err
+		}
+		e.ws()
+		if !e.consume(':') {
+			return e.errorf("expected colon")
+		}
+		e.ws()
+
+		f, ok := lookupField(key)
+		switch {
+		case !ok || !want.has(f):
+			err = e.skip()
+		case f == fieldBrowsers:
+			err = e.browsers()
+		default:
+			err = e.stringField(r.field(f))
+		}
+		if err != nil {
+			return err
+		}
+
+		e.ws()
+		if e.consume(',') {
+			continue
+		}
+		if e.consume('}') {
+			return e.end()
+		}
+		return e.errorf("expected comma or end of object")
+	}
+}
+
+// lookupField matches key case insensitively like encoding/json does
+func lookupField(key []byte) (field, bool) {
+	for f, name := range fieldNames {
+		if bytes.EqualFold(key, name) {
+			return field(f), true
+		}
+	}
+	return 0, false
+}
+
+func (e *extractor) end() error {
+	e.ws()
+	if e.pos != len(e.data) {
+		return e.errorf("unexpected data after object")
+	}
+	return nil
+}
+
+func (e *extractor) peek() byte {
+	if e.pos < len(e.data) {
+		return e.data[e.pos]
+	}
+	return 0
+}
+
+func (e *extractor) consume(c byte) bool {
+	if e.peek() == c {
+		e.pos++
+		return true
+	}
+	return false
+}
+
+func (e *extractor) literal(s string) bool {
+	if len(e.data)-e.pos >= len(s) && string(e.data[e.pos:e.pos+len(s)]) == s {
+		e.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (e *extractor) ws() {
+	for e.pos < len(e.data) {
+		switch e.data[e.pos] {
+		case ' ', '\t', '\n', '\r':
+			e.pos++
+		default:
+			return
+		}
+	}
+}
+
+// stringField sets string or leaves it as is for null
+func (e *extractor) stringField(dst *[]byte) error {
+	if e.literal("null") {
+		return nil
+	}
+	if e.peek() != '"' {
+		return e.errorf("expected string")
+	}
+	s, err := e.string()
+	if err != nil {
+		return err
+	}
+	*dst = s
+	return nil
+}
+
+func (e *extractor) browsers() error {
+	r := e.rec
+	r.Browsers = r.Browsers[:0]
+	if e.literal("null") {
+		r.Browsers = nil
+		return nil
+	}
+	if !e.consume('[') {
+		return e.errorf("expected array")
+	}
+	e.ws()
+	if e.consume(']') {
+		return nil
+	}
+	for {
+		e.ws()
+		var s []byte
+		if err := e.stringField(&s); err != nil {
+			return err
+		}
+		r.Browsers = append(r.Browsers, s)
+		e.ws()
+		if e.consume(',') {
+			continue
+		}
+		if e.consume(']') {
+			return nil
+		}
+		return e.errorf("expected comma or end of array")
+	}
+}
+
+// string returns string value starting at the quote, it points into data
+// unless there are escapes or invalid utf-8 to be replaced
+func (e *extractor) string() ([]byte, error) {
+	start := e.pos + 1
+	ascii := true
+	for i := start; i < len(e.data); i++ {
+		switch c := e.data[i]; {
+		case c == '"':
+			s := e.data[start:i]
+			if !ascii && !utf8.Valid(s) {
+				return e.unquote()
+			}
+			e.pos = i + 1
+			return s, nil
+		case c == '\\' || c < 0x20:
+			return e.unquote()
+		case c >= utf8.RuneSelf:
+			ascii = false
+		}
+	}
+	return e.unquote()
+}
+
+// unquote decodes string starting at the quote into scratch buffer
+func (e *extractor) unquote() ([]byte, error) {
+	buf := e.rec.scratch
+	begin := len(buf)
+	i := e.pos + 1
+	for i < len(e.data) {
+		c := e.data[i]
+		switch {
+		case c == '"':
+			e.pos = i + 1
+			e.rec.scratch = buf
+			return buf[begin:len(buf):len(buf)], nil
+		case c < 0x20:
+			e.pos = i
+			return nil, e.errorf("control character in string")
+		case c == '\\':
+			if i+1 >= len(e.data) {
+				e.pos = i
+				return nil, e.errorf("unterminated escape")
+			}
+			switch esc := e.data[i+1]; esc {
+			case '"', '\\', '/':
+				buf = append(buf, esc)
+			case 'b':
+				buf = append(buf, '\b')
+			case 'f':
+				buf = append(buf, '\f')
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'u':
+				r, ok := hex4(e.data[i+2:])
+				if !ok {
+					e.pos = i
+					return nil, e.errorf("bad unicode escape")
+				}
+				i += 6
+				if utf16.IsSurrogate(r) {
+					if len(e.data) >= i+6 && e.data[i] == '\\' && e.data[i+1] == 'u' {
+						if r2, ok := hex4(e.data[i+2:]); ok {
+							if dec := utf16.DecodeRune(r, r2); dec != utf8.RuneError {
+								buf = utf8.AppendRune(buf, dec)
+								i += 6
+								continue
+							}
+						}
+					}
+					r = utf8.RuneError
+				}
+				buf = utf8.AppendRune(buf, r)
+				continue
+			default:
+				e.pos = i
+				return nil, e.errorf("bad escape")
+			}
+			i += 2
+		case c < utf8.RuneSelf:
+			buf = append(buf, c)
+			i++
+		default:
+			r, size := utf8.DecodeRune(e.data[i:])
+			if r == utf8.RuneError && size == 1 {
+				buf = utf8.AppendRune(buf, utf8.RuneError)
+			} else {
+				buf = append(buf, e.data[i:i+size]...)
+			}
+			i += size
+		}
+	}
+	e.pos = i
+	return nil, e.errorf("unterminated string")
+}
+
+func hex4(b []byte) (rune, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range b[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c -= '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(c)
+	}
+	return r, true
+}
+
+// skip passes over any value
+func (e *extractor) skip() error {
+	switch c := e.peek(); {
+	case c == '"':
+		_, err := e.string()
+		return err
+	case c == '{':
+		e.pos++
+		e.ws()
+		if e.consume('}') {
+			return nil
+		}
+		for {
+			e.ws()
+			if e.peek() != '"' {
+				return e.errorf("expected key")
+			}
+			if _, err := e.string(); err != nil {
+				return err
+			}
+			e.ws()
+			if !e.consume(':') {
+				return e.errorf("expected colon")
+			}
+			e.ws()
+			if err := e.skip(); err != nil {
+				return err
+			}
+			e.ws()
+			if e.consume(',') {
+				continue
+			}
+			if e.consume('}') {
+				return nil
+			}
+			return e.errorf("expected comma or end of object")
+		}
+	case c == '[':
+		e.pos++
+		e.ws()
+		if e.consume(']') {
+			return nil
+		}
+		for {
+			e.ws()
+			if err := e.skip(); err != nil {
+				return err
+			}
+			e.ws()
+			if e.consume(',') {
+				continue
+			}
+			if e.consume(']') {
+				return nil
+			}
+			return e.errorf("expected comma or end of array")
+		}
+	case c == '-' || ('0' <= c && c <= '9'):
+		return e.number()
+	case e.literal("true"), e.literal("false"), e.literal("null"):
+		return nil
+	}
+	return e.errorf("unexpected value")
+}
+
+func (e *extractor) digits() int {
+	n := 0
+	for e.pos < len(e.data) && '0' <= e.data[e.pos] && e.data[e.pos] <= '9' {
+		e.pos++
+		n++
+	}
+	return n
+}
+
+func (e *extractor) number() error {
+	e.consume('-')
+	if !e.consume('0') && e.digits() == 0 {
+		return e.errorf("bad number")
+	}
+	if e.consume('.') && e.digits() == 0 {
+		return e.errorf("bad number")
+	}
+	if e.consume('e') || e.consume('E') {
+		if !e.consume('+') {
+			e.consume('-')
+		}
+		if e.digits() == 0 {
+			return e.errorf("bad number")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package fast
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// extracted turns record into User to be compared with encoding/json result,
+// absent browsers are not told apart from empty ones
+func extracted(r *Record) User {
+	u := User{
+		Company: string(r.Company),
+		Country: string(r.Country),
+		Email:   string(r.Email),
+		Job:     string(r.Job),
+		Name:    string(r.Name),
+		Phone:   string(r.Phone),
+	}
+	for _, b := range r.Browsers {
+		u.Browsers = append(u.Browsers, string(b))
+	}
+	return u
+}
+
+func unmarshal(line []byte) (User, error) {
+	u := User{}
+	err := json.Unmarshal(line, &u)
+	if len(u.Browsers) == 0 {
+		u.Browsers = nil
+	}
+	return u, err
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want User
+	}{
+		{"plain", `{"browsers":["a","b"],"name":"n","email":"e@x","job":"j"}`,
+			User{Browsers: []string{"a", "b"}, Name: "n", Email: "e@x", Job: "j"}},
+		{"spaces", " { \"name\" : \"n\" ,\t\"browsers\":[ ] }\r\n", User{Name: "n"}},
+		{"escapes", `{"name":"a\"b\\c\/d\nA\u00e9\ud83d\ude00\t"}`, User{Name: "a\"b\\c/d\nAé😀\t"}},
+		{"skip", `{"x":{"y":[1,-2.5e+3,true,false,null,{"z":"\""}]},"name":"n"}`, User{Name: "n"}},
+		{"nulls", `{"name":"n","name":null,"browsers":null,"email":null}`, User{Name: "n"}},
+		{"null elements", `{"browsers":["a",null]}`, User{Browsers: []string{"a", ""}}},
+		{"case", `{"NAME":"n","Email":"e"}`, User{Name: "n", Email: "e"}},
+		{"escaped key", `{"n\u0061me":"n"}`, User{Name: "n"}},
+		{"duplicate", `{"browsers":["a"],"browsers":["b","c"],"name":"1","name":"2"}`, User{Browsers: []string{"b", "c"}, Name: "2"}},
+		{"invalid utf8", "{\"name\":\"a\xffb\"}", User{Name: "a�b"}},
+		{"lone surrogate", `{"name":"\ud83dA"}`, User{Name: "�A"}},
+		{"null", `null`, User{}},
+	}
+
+	r := &Record{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.Extract([]byte(tt.line), allFields); err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+			if got := extracted(r); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract() = %#v, want %#v", got, tt.want)
+			}
+
+			want, err := unmarshal([]byte(tt.line))
+			if err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if !reflect.DeepEqual(want, tt.want) {
+				t.Errorf("json.Unmarshal() = %#v, test expects %#v", want, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`[]`,
+		`"name"`,
+		`{"name":"n"`,
+		`{"name":"n",}`,
+		`{"name" "n"}`,
+		`{"name":1}`,
+		`{"browsers":"a"}`,
+		`{"browsers":["a",1]}`,
+		`{"browsers":["a"`,
+		`{"x":01}`,
+		`{"x":-}`,
+		`{"x":1.}`,
+		`{"x":1e}`,
+		`{"x":tru}`,
+		`{"x":[1,]}`,
+		`{"x":{"a" 1}}`,
+		`{"name":"a\x"}`,
+		`{"name":"a\u12"}`,
+		"{\"name\":\"a\tb\"}",
+		`{"name":"a`,
+		`{} {}`,
+	}
+	r := &Record{}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			if err := r.Extract([]byte(tt), allFields); err == nil {
+				t.Errorf("Extract() = %#v, want error", extracted(r))
+			}
+		})
+	}
+}
+
+func TestExtractAllocs(t *testing.T) {
+	data, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	lines = append(lines, []byte(`{"browsers":["Android"],"name":"é","email":"a@b"}`))
+
+	r := &Record{}
+	want := fieldMask(1<<fieldBrowsers | 1<<fieldName | 1<<fieldEmail)
+	for _, line := range lines {
+		if err := r.Extract(line, want); err != nil {
+			t.Fatal(err)
+		}
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		for _, line := range lines {
+			r.Extract(line, want)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Extract() allocs = %v, want 0", allocs)
+	}
+}
+
+func FuzzExtract(f *testing.F) {
+	data, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, line := range bytes.Split(data, []byte("\n"))[:20] {
+		f.Add(line)
+	}
+	f.Add([]byte(`{"name":"a\"b\\c\/d\nAé😀","browsers":[null,"\ud800"]}`))
+	f.Add([]byte("{\"EMAIL\":\"a\xffb\",\"x\":[{},[],-0.1E5]}"))
+
+	r := &Record{}
+	f.Fuzz(func(t *testing.T, line []byte) {
+		want, err := unmarshal(line)
+		if err != nil {
+			return
+		}
+		if err := r.Extract(line, allFields); err != nil {
+			t.Fatalf("Extract(%q) error = %v, json decodes it into %#v", line, err, want)
+		}
+		if got := extracted(r); !reflect.DeepEqual(got, want) {
+			t.Errorf("Extract(%q) = %#v, json gives %#v", line, got, want)
+		}
+	})
+}
@@ -1,62 +1,214 @@
 package fast
 
 import (
-	"bytes"
 	"fmt"
+	"hash"
 	"io"
-	"strings"
 )
 
-type (
-	//easyjson:json
-	User struct {
-		Browsers []string `json:"browsers,nocopy"`
-		Company  string   `json:"company,nocopy"`
-		Country  string   `json:"country,nocopy"`
-		Email    string   `json:"email,nocopy"`
-		Job      string   `json:"job,nocopy"`
-		Name     string   `json:"name,nocopy"`
-		Phone    string   `json:"phone,nocopy"`
+type User struct {
+	Browsers []string `json:"browsers"`
+	Company  string   `json:"company"`
+	Country  string   `json:"country"`
+	Email    string   `json:"email"`
+	Job      string   `json:"job"`
+	Name     string   `json:"name"`
+	Phone    string   `json:"phone"`
+}
+
+var defaultFilter = MustCompile(DefaultFilter)
+
+// Options tune the search, zero value searches with DefaultFilter
+type Options struct {
+	Filter *Filter
+	// Lenient skips malformed lines instead of stopping at the first one,
+	// they are returned in *MalformedError after the whole output is written
+	Lenient bool
+	// FileLines numbers lines of every SearchInputs input from zero,
+	// results are prefixed with input name then
+	FileLines bool
+	// Format of results, FormatText by default
+	Format Format
+	// Mask of emails in results, MaskAt by default
+	Mask Mask
+	// MaskKey is hmac key of MaskHash, it is required by it
+	MaskKey []byte
+	// Offset skips first matching users and Limit is how many of the rest are
+	// written, 0 is all of them. found in summaries still counts every match
+	Offset int
+	Limit  int
+}
+
+// LineError is a line of users file that could not be parsed
+type LineError struct {
+	// input name, empty for a single input
+	Input string
+	Line  int
+	Err   error
+}
+
+func (e *LineError) Error() string {
+	if e.Input != "" {
+		return fmt.Sprintf("%s: line %d: %v", e.Input, e.Line, e.Err)
 	}
-)
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
 
-func FastSearch(out io.Writer, data []byte) {
-	seenBrowsers := map[string]interface{}{}
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// MalformedError lists lines skipped by lenient search in input order
+type MalformedError struct {
+	Lines []*LineError
+}
 
-	fmt.Fprintln(out, "found users:")
+func (e *MalformedError) Error() string {
+	return fmt.Sprintf("%d malformed lines, first %v", len(e.Lines), e.Lines[0])
+}
 
-	user := User{}
-	for i, line := range bytes.Split(data, []byte("\n")) {
-		// err := json.Unmarshal(line, &user)
-		err := user.UnmarshalJSON(line)
-		if err != nil {
-			panic(err)
-		}
+// searcher keeps state of a single search
+type searcher struct {
+	out          io.Writer
+	filter       *Filter
+	seenBrowsers map[string]interface{}
+	seenFunc     func([]byte)
+	lenient      bool
+	malformed    []*LineError
+	// current input name, it prefixes line numbers if showInput is set
+	input     string
+	showInput bool
+	format    Format
+	mask      Mask
+	mac       hash.Hash
+	offset    int
+	limit     int
+	// found counts matching users, written counts the ones in output
+	found   int
+	written int
+	// browsers of current line matching filter
+	matched [][]byte
+	// fields to be extracted from lines
+	want    fieldMask
+	rec     Record
+	buf     []byte
+	scratch []byte
+}
 
-		isAndroid := false
-		isMSIE := false
-
-		for _, browser := range user.Browsers {
-			if strings.Contains(browser, "Android") {
-				seenBrowsers[browser] = nil
-				isAndroid = true
-				continue
-			}
-
-			if strings.Contains(browser, "MSIE") {
-				seenBrowsers[browser] = nil
-				isMSIE = true
-				continue
-			}
-		}
+func newSearcher(out io.Writer, opts Options) *searcher {
+	s := &searcher{
+		out:          out,
+		filter:       opts.Filter,
+		seenBrowsers: map[string]interface{}{},
+		lenient:      opts.Lenient,
+		showInput:    opts.FileLines,
+		format:       opts.Format,
+		mask:         opts.Mask,
+		offset:       opts.Offset,
+		limit:        opts.Limit,
+	}
+	if s.filter == nil {
+		s.filter = defaultFilter
+	}
+	if s.mask == MaskHash {
+		s.mac = newMaskHash(opts.MaskKey)
+	}
+	s.seenFunc = s.seen
+	s.want = s.filter.fields | 1<<fieldBrowsers | 1<<fieldName | 1<<fieldEmail
+	return s
+}
+
+func (s *searcher) begin() error {
+	_, err := s.out.Write(s.beginning())
+	return err
+}
+
+func (s *searcher) seen(browser []byte) {
+	s.matched = append(s.matched, browser)
+	// lookup doesnt allocate, only new browsers are copied
+	if _, ok := s.seenBrowsers[string(browser)]; !ok {
+		s.seenBrowsers[string(browser)] = nil
+	}
+}
 
-		if !(isAndroid && isMSIE) {
-			continue
+// line handles i-th line of users file
+func (s *searcher) line(i int, line []byte) error {
+	rec := &s.rec
+	if err := rec.Extract(line, s.want); err != nil {
+		lerr := &LineError{Input: s.input, Line: i, Err: err}
+		if s.lenient {
+			s.malformed = append(s.malformed, lerr)
+			return nil
 		}
+		return lerr
+	}
+	return s.record(i, rec)
+}
+
+// record handles user of i-th line
+func (s *searcher) record(i int, rec *Record) error {
+	s.matched = s.matched[:0]
+	s.filter.Seen(rec, s.seenFunc)
+	if !s.filter.Match(rec) {
+		return nil
+	}
+
+	s.found++
+	if s.found <= s.offset || (s.limit > 0 && s.written >= s.limit) {
+		return nil
+	}
+	_, err := s.out.Write(s.result(i, rec))
+	s.written++
+	return err
+}
+
+func (s *searcher) end() error {
+	if _, err := s.out.Write(s.ending()); err != nil {
+		return err
+	}
+	if len(s.malformed) > 0 {
+		return &MalformedError{Lines: s.malformed}
+	}
+	return nil
+}
+
+func FastSearch(out io.Writer, data []byte) {
+	if err := Search(out, data, Options{}); err != nil {
+		panic(err)
+	}
+}
 
-		mail := strings.Replace(user.Email, "@", " [at] ", 1)
-		fmt.Fprintf(out, "[%d] %s <%s>\n", i, user.Name, mail)
+// Search looks for users in data, which is users file loaded into memory.
+// a malformed line stops it with *LineError unless opts.Lenient is set
+func Search(out io.Writer, data []byte, opts Options) error {
+	if err := opts.check(); err != nil {
+		return err
+	}
+	s := newSearcher(out, opts)
+	if err := s.begin(); err != nil {
+		return err
 	}
+	c := chunk{data: data, last: true}
+	if err := c.lines(s.line); err != nil {
+		return err
+	}
+	return s.end()
+}
 
-	fmt.Fprintln(out, "\nTotal unique browsers", len(seenBrowsers))
+// SearchRecords is Search over users parsed once by ParseRecords,
+// so repeated searches dont parse users file again
+func SearchRecords(out io.Writer, recs []Record, opts Options) error {
+	if err := opts.check(); err != nil {
+		return err
+	}
+	s := newSearcher(out, opts)
+	if err := s.begin(); err != nil {
+		return err
+	}
+	for i := range recs {
+		if err := s.record(i, &recs[i]); err != nil {
+			return err
+		}
+	}
+	return s.end()
 }
@@ -1,159 +0,0 @@
-// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
-
-package fast
-
-import (
-	json "encoding/json"
-	easyjson "github.com/mailru/easyjson"
-	jlexer "github.com/mailru/easyjson/jlexer"
-	jwriter "github.com/mailru/easyjson/jwriter"
-)
-
-// suppress unused package warning
-var (
-	_ *json.RawMessage
-	_ *jlexer.Lexer
-	_ *jwriter.Writer
-	_ easyjson.Marshaler
-)
-
-func easyjson3486653aDecodeLocalhostCourseraHw3BenchFast(in *jlexer.Lexer, out *User) {
-	isTopLevel := in.IsStart()
-	if in.IsNull() {
-		if isTopLevel {
-			in.Consumed()
-		}
-		in.Skip()
-		return
-	}
-	in.Delim('{')
-	for !in.IsDelim('}') {
-		key := in.UnsafeFieldName(false)
-		in.WantColon()
-		if in.IsNull() {
-			in.Skip()
-			in.WantComma()
-			continue
-		}
-		switch key {
-		case "browsers":
-			if in.IsNull() {
-				in.Skip()
-				out.Browsers = nil
-			} else {
-				in.Delim('[')
-				if out.Browsers == nil {
-					if !in.IsDelim(']') {
-						out.Browsers = make([]string, 0, 4)
-					} else {
-						out.Browsers = []string{}
-					}
-				} else {
-					out.Browsers = (out.Browsers)[:0]
-				}
-				for !in.IsDelim(']') {
-					var v1 string
-					v1 = string(in.UnsafeString())
-					out.Browsers = append(out.Browsers, v1)
-					in.WantComma()
-				}
-				in.Delim(']')
-			}
-		case "company":
-			out.Company = string(in.UnsafeString())
-		case "country":
-			out.Country = string(in.UnsafeString())
-		case "email":
-			out.Email = string(in.UnsafeString())
-		case "job":
-			out.Job = string(in.UnsafeString())
-		case "name":
-			out.Name = string(in.UnsafeString())
-		case "phone":
-			out.Phone = string(in.UnsafeString())
-		default:
-			in.SkipRecursive()
-		}
-		in.WantComma()
-	}
-	in.Delim('}')
-	if isTopLevel {
-		in.Consumed()
-	}
-}
-func easyjson3486653aEncodeLocalhostCourseraHw3BenchFast(out *jwriter.Writer, in User) {
-	out.RawByte('{')
-	first := true
-	_ = first
-	{
-		const prefix string = ",\"browsers\":"
-		out.RawString(prefix[1:])
-		if in.Browsers == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
-			out.RawString("null")
-		} else {
-			out.RawByte('[')
-			for v2, v3 := range in.Browsers {
-				if v2 > 0 {
-					out.RawByte(',')
-				}
-				out.String(string(v3))
-			}
-			out.RawByte(']')
-		}
-	}
-	{
-		const prefix string = ",\"company\":"
-		out.RawString(prefix)
-		out.String(string(in.Company))
-	}
-	{
-		const prefix string = ",\"country\":"
-		out.RawString(prefix)
-		out.String(string(in.Country))
-	}
-	{
-		const prefix string = ",\"email\":"
-		out.RawString(prefix)
-		out.String(string(in.Email))
-	}
-	{
-		const prefix string = ",\"job\":"
-		out.RawString(prefix)
-		out.String(string(in.Job))
-	}
-	{
-		const prefix string = ",\"name\":"
-		out.RawString(prefix)
-		out.String(string(in.Name))
-	}
-	{
-		const prefix string = ",\"phone\":"
-		out.RawString(prefix)
-		out.String(string(in.Phone))
-	}
-	out.RawByte('}')
-}
-
-// MarshalJSON supports json.Marshaler interface
-func (v User) MarshalJSON() ([]byte, error) {
-	w := jwriter.Writer{}
-	easyjson3486653aEncodeLocalhostCourseraHw3BenchFast(&w, v)
-	return w.Buffer.BuildBytes(), w.Error
-}
-
-// MarshalEasyJSON supports easyjson.Marshaler interface
-func (v User) MarshalEasyJSON(w *jwriter.Writer) {
-	easyjson3486653aEncodeLocalhostCourseraHw3BenchFast(w, v)
-}
-
-// UnmarshalJSON supports json.Unmarshaler interface
-func (v *User) UnmarshalJSON(data []byte) error {
-	r := jlexer.Lexer{Data: data}
-	easyjson3486653aDecodeLocalhostCourseraHw3BenchFast(&r, v)
-	return r.Error()
-}
-
-// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
-func (v *User) UnmarshalEasyJSON(l *jlexer.Lexer) {
-	easyjson3486653aDecodeLocalhostCourseraHw3BenchFast(l, v)
-}
@@ -0,0 +1,232 @@
+package fast
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// searches runs data through every search function
+var searches = map[string]func(out *bytes.Buffer, data []byte, opts Options) error{
+	"Search": func(out *bytes.Buffer, data []byte, opts Options) error {
+		return Search(out, data, opts)
+	},
+	"SearchReader": func(out *bytes.Buffer, data []byte, opts Options) error {
+		return SearchReader(out, bytes.NewReader(data), opts)
+	},
+	"SearchParallel": func(out *bytes.Buffer, data []byte, opts Options) error {
+		return SearchParallel(out, data, opts, 3)
+	},
+}
+
+func TestSearchTrailingNewline(t *testing.T) {
+	users, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := new(bytes.Buffer)
+	if err := Search(want, users, Options{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, search := range searches {
+		got := new(bytes.Buffer)
+		if err := search(got, append(users, '\n'), Options{}); err != nil {
+			t.Errorf("%s() error = %v", name, err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("%s() results not match\nGot:\n%v\nExpected:\n%v", name, got, want)
+		}
+	}
+}
+
+func TestSearchLenient(t *testing.T) {
+	users, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(users, []byte("\n"))
+
+	broken := map[int]string{
+		3:   `{"browsers": ["MSIE"`,
+		500: ``,
+		999: `not json`,
+	}
+	for i, line := range broken {
+		lines[i] = []byte(line)
+	}
+	data := bytes.Join(lines, []byte("\n"))
+	want := lenientWant(t, lines, broken)
+
+	for name, search := range searches {
+		err := search(new(bytes.Buffer), data, Options{})
+		lerr := &LineError{}
+		if !errors.As(err, &lerr) || lerr.Line != 3 {
+			t.Errorf("%s() error = %v, want line 3 error", name, err)
+		}
+
+		got := new(bytes.Buffer)
+		err = search(got, data, Options{Lenient: true})
+		merr := &MalformedError{}
+		if !errors.As(err, &merr) {
+			t.Fatalf("%s(lenient) error = %v, want *MalformedError", name, err)
+		}
+		nums := []int{}
+		for _, l := range merr.Lines {
+			nums = append(nums, l.Line)
+			if !errors.Is(l, errSyntax) {
+				t.Errorf("%s(lenient) line %d error = %v, want syntax error", name, l.Line, l.Err)
+			}
+		}
+		if !reflect.DeepEqual(nums, []int{3, 500, 999}) {
+			t.Errorf("%s(lenient) malformed lines = %v, want [3 500 999]", name, nums)
+		}
+		if got.String() != want {
+			t.Errorf("%s(lenient) results not match\nGot:\n%v\nExpected:\n%v", name, got, want)
+		}
+	}
+}
+
+// lenientWant is the output of valid lines only, numbered as in the whole file
+func lenientWant(t *testing.T, lines [][]byte, broken map[int]string) string {
+	out := new(bytes.Buffer)
+	s := newSearcher(out, Options{})
+	s.begin()
+	for i, line := range lines {
+		if _, ok := broken[i]; ok {
+			continue
+		}
+		if err := s.line(i, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.end(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestEach(t *testing.T) {
+	data := `{"name":"a","country":"Malta","browsers":["MSIE 8.0"]}
+{"name":"b","country":"Chad"}
+{broken
+{"name":"c","country":"Malta","phone":"1"}
+`
+	each := func(opts Options) ([]string, error) {
+		rv := []string{}
+		err := Each(strings.NewReader(data), opts, func(i int, rec *Record) error {
+			rv = append(rv, fmt.Sprintf("%d %s %s %s %d", i, rec.Name, rec.Country, rec.Phone, len(rec.Browsers)))
+			return nil
+		})
+		return rv, err
+	}
+
+	got, err := each(Options{})
+	lerr := &LineError{}
+	if !errors.As(err, &lerr) || lerr.Line != 2 {
+		t.Errorf("Each() error = %v, want line 2 error", err)
+	}
+	if want := []string{"0 a Malta  1", "1 b Chad  0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Each() = %q, want %q", got, want)
+	}
+
+	got, err = each(Options{Filter: MustCompile(`country = "Malta"`), Lenient: true})
+	merr := &MalformedError{}
+	if !errors.As(err, &merr) || len(merr.Lines) != 1 {
+		t.Errorf("Each(lenient) error = %v, want a malformed line", err)
+	}
+	if want := []string{"0 a Malta  1", "3 c Malta 1 0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Each(lenient) = %q, want %q", got, want)
+	}
+}
+
+func TestSearchRecords(t *testing.T) {
+	users, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs, err := ParseRecords(bytes.NewReader(users))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []Options{
+		{},
+		{Format: FormatJSON, Offset: 3, Limit: 5},
+		{Filter: MustCompile(`browsers ~ "Opera" AND country = "Russia"`), Format: FormatCSV},
+		{Filter: MustCompile(`NOT browsers ~ "Android"`), Format: FormatNDJSON, Mask: MaskRaw},
+	} {
+		want := new(bytes.Buffer)
+		if err := Search(want, users, opts); err != nil {
+			t.Fatal(err)
+		}
+		// the same records are searched again
+		for k := 0; k < 2; k++ {
+			got := new(bytes.Buffer)
+			if err := SearchRecords(got, recs, opts); err != nil {
+				t.Fatalf("SearchRecords() error = %v", err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("SearchRecords(%+v) results not match\nGot:\n%v\nExpected:\n%v", opts, got, want)
+			}
+		}
+	}
+
+	if _, err := ParseRecords(strings.NewReader("{}\n{broken\n")); err == nil {
+		t.Errorf("ParseRecords() error = nil for malformed line")
+	}
+}
+
+func TestSearchInputs(t *testing.T) {
+	users, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(users, []byte("\n"))
+	parts := [][]byte{
+		bytes.Join(lines[:300], []byte("\n")),
+		bytes.Join(lines[300:301], []byte("\n")),
+		{},
+		bytes.Join(lines[301:], []byte("\n")),
+	}
+	in := []Input{}
+	for i := range parts {
+		i := i
+		in = append(in, Input{Name: fmt.Sprint("part", i), Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(parts[i])), nil
+		}})
+	}
+
+	want := new(bytes.Buffer)
+	if err := Search(want, users, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	got := new(bytes.Buffer)
+	if err := SearchInputs(got, in, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("SearchInputs() results not match\nGot:\n%v\nExpected:\n%v", got, want)
+	}
+
+	got.Reset()
+	if err := SearchInputs(got, in, Options{FileLines: true}); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"\n[part0:1] ", "\n[part3:10] "} {
+		if !strings.Contains(got.String(), s) {
+			t.Errorf("SearchInputs(FileLines) has no %q in\n%s", s, got)
+		}
+	}
+
+	parts[3] = []byte("{}\n{broken")
+	err = SearchInputs(new(bytes.Buffer), in, Options{FileLines: true})
+	if err == nil || err.Error() != "part3: line 1: invalid json: expected key at 1" {
+		t.Errorf("SearchInputs() error = %v, want part3 line 1 error", err)
+	}
+}
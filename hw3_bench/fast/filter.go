@@ -0,0 +1,379 @@
+package fast
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DefaultFilter is what FastSearch has always been looking for
+const DefaultFilter = `browsers ~ "Android" AND browsers ~ "MSIE"`
+
+// Filter is a compiled expression over User fields like
+//
+//	browsers ~ "Android" AND (country = "Russia" OR NOT company = "Flashpoint")
+//
+// = means equal, ~ means contains, != and !~ are their negations.
+// browsers condition holds if any browser satisfies it, negated one
+// holds if none does. AND binds tighter than OR, keywords are case insensitive
+type Filter struct {
+	expr string
+	root node
+	// fields used by the expression
+	fields fieldMask
+	// positive browsers conditions, browsers matching them are counted as seen
+	collect []*cond
+}
+
+type field int
+
+const (
+	fieldBrowsers field = iota
+	fieldCompany
+	fieldCountry
+	fieldEmail
+	fieldJob
+	fieldName
+	fieldPhone
+)
+
+var fields = map[string]field{
+	"browsers": fieldBrowsers,
+	"company":  fieldCompany,
+	"country":  fieldCountry,
+	"email":    fieldEmail,
+	"job":      fieldJob,
+	"name":     fieldName,
+	"phone":    fieldPhone,
+}
+
+type node interface {
+	match(r *Record) bool
+}
+
+type cond struct {
+	field    field
+	contains bool
+	negate   bool
+	value    []byte
+}
+
+func (c *cond) test(s []byte) bool {
+	if c.contains {
+		return bytes.Contains(s, c.value)
+	}
+	return bytes.Equal(s, c.value)
+}
+
+func (c *cond) match(r *Record) bool {
+	if c.field != fieldBrowsers {
+		return c.test(*r.field(c.field)) != c.negate
+	}
+	for _, b := range r.Browsers {
+		if c.test(b) {
+			return !c.negate
+		}
+	}
+	return c.negate
+}
+
+type and []node
+
+func (n and) match(r *Record) bool {
+	for _, c := range n {
+		if !c.match(r) {
+			return false
+		}
+	}
+	return true
+}
+
+type or []node
+
+func (n or) match(r *Record) bool {
+	for _, c := range n {
+		if c.match(r) {
+			return true
+		}
+	}
+	return false
+}
+
+type not struct {
+	node
+}
+
+func (n not) match(r *Record) bool {
+	return !n.node.match(r)
+}
+
+// Compile parses filter expression
+func Compile(expr string) (*Filter, error) {
+	p := &parser{lex: lexer{src: expr}}
+	p.next()
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+	f := &Filter{expr: expr, root: root, fields: p.fields, collect: p.collect}
+	return f, nil
+}
+
+// MustCompile is Compile that panics on error
+func MustCompile(expr string) *Filter {
+	f, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match reports whether user satisfies filter
+func (f *Filter) Match(r *Record) bool {
+	return f.root.match(r)
+}
+
+// Seen calls seen for every user browser matching a positive browsers condition
+func (f *Filter) Seen(r *Record, seen func(browser []byte)) {
+	for _, b := range r.Browsers {
+		for _, c := range f.collect {
+			if c.test(b) {
+				seen(b)
+				break
+			}
+		}
+	}
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokIdent
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of filter"
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && strings.IndexByte(" \t\r\n", l.src[l.pos]) >= 0 {
+		l.pos++
+	}
+	start := l.pos
+	if l.pos == len(l.src) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	switch c := l.src[l.pos]; {
+	case c == '(':
+		l.pos++
+		return token{kind: tokLParen, text: "(", pos: start}, nil
+	case c == ')':
+		l.pos++
+		return token{kind: tokRParen, text: ")", pos: start}, nil
+	case c == '=' || c == '~':
+		l.pos++
+		return token{kind: tokOp, text: l.src[start:l.pos], pos: start}, nil
+	case c == '!':
+		if l.pos+1 < len(l.src) && (l.src[l.pos+1] == '=' || l.src[l.pos+1] == '~') {
+			l.pos += 2
+			return token{kind: tokOp, text: l.src[start:l.pos], pos: start}, nil
+		}
+		return token{}, fmt.Errorf("filter: unexpected '!' at %d", start)
+	case c == '"':
+		return l.string()
+	case isIdent(c):
+		for l.pos < len(l.src) && isIdent(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}, nil
+	default:
+		return token{}, fmt.Errorf("filter: unexpected %q at %d", c, start)
+	}
+}
+
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	sb := strings.Builder{}
+	for l.pos++; l.pos < len(l.src); l.pos++ {
+		switch c := l.src[l.pos]; c {
+		case '"':
+			l.pos++
+			return token{kind: tokString, text: sb.String(), pos: start}, nil
+		case '\\':
+			l.pos++
+			if l.pos == len(l.src) || (l.src[l.pos] != '"' && l.src[l.pos] != '\\') {
+				return token{}, fmt.Errorf("filter: bad escape in string at %d", start)
+			}
+			sb.WriteByte(l.src[l.pos])
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return token{}, fmt.Errorf("filter: unterminated string at %d", start)
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+type parser struct {
+	lex lexer
+	tok token
+	err error
+	// NOT operators around current position
+	negated bool
+	fields  fieldMask
+	collect []*cond
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lex.next()
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	return fmt.Errorf("filter: "+format+" at %d", append(args, p.tok.pos)...)
+}
+
+func (p *parser) keyword(kw string) bool {
+	return p.tok.kind == tokIdent && strings.EqualFold(p.tok.text, kw)
+}
+
+func (p *parser) or() (node, error) {
+	n, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	rv := or{n}
+	for p.keyword("OR") {
+		p.next()
+		n, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, n)
+	}
+	if len(rv) == 1 {
+		return rv[0], nil
+	}
+	return rv, nil
+}
+
+func (p *parser) and() (node, error) {
+	n, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	rv := and{n}
+	for p.keyword("AND") {
+		p.next()
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, n)
+	}
+	if len(rv) == 1 {
+		return rv[0], nil
+	}
+	return rv, nil
+}
+
+func (p *parser) unary() (node, error) {
+	switch {
+	case p.err != nil:
+		return nil, p.err
+	case p.keyword("NOT"):
+		p.next()
+		p.negated = !p.negated
+		n, err := p.unary()
+		p.negated = !p.negated
+		if err != nil {
+			return nil, err
+		}
+		return not{n}, nil
+	case p.tok.kind == tokLParen:
+		p.next()
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.errorf("expected ) instead of %s", p.tok)
+		}
+		p.next()
+		return n, nil
+	}
+	return p.cond()
+}
+
+func (p *parser) cond() (node, error) {
+	if p.tok.kind != tokIdent {
+		return nil, p.errorf("expected field instead of %s", p.tok)
+	}
+	f, ok := fields[strings.ToLower(p.tok.text)]
+	if !ok {
+		return nil, p.errorf("unknown field %s", p.tok)
+	}
+	p.next()
+
+	if p.tok.kind != tokOp {
+		return nil, p.errorf("expected operator instead of %s", p.tok)
+	}
+	c := &cond{
+		field:    f,
+		contains: strings.HasSuffix(p.tok.text, "~"),
+		negate:   strings.HasPrefix(p.tok.text, "!"),
+	}
+	p.next()
+
+	if p.tok.kind != tokString {
+		return nil, p.errorf("expected string instead of %s", p.tok)
+	}
+	c.value = []byte(p.tok.text)
+	p.next()
+
+	p.fields |= 1 << c.field
+	if c.field == fieldBrowsers && !c.negate && !p.negated {
+		p.collect = append(p.collect, c)
+	}
+	return c, nil
+}
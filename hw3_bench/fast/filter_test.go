@@ -0,0 +1,121 @@
+package fast
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func record(t *testing.T, u *User) *Record {
+	line, err := json.Marshal(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Record{}
+	if err := r.Extract(line, allFields); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestFilterMatch(t *testing.T) {
+	u := record(t, &User{
+		Browsers: []string{"Mozilla/5.0 (Android 4.4)", "Opera/9.80 (MSIE 9.0)", "Links"},
+		Company:  "Flashpoint",
+		Country:  "Russia",
+		Email:    "jm@muxo.edu",
+		Name:     "Sharon Crawford",
+	})
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{DefaultFilter, true},
+		{`browsers ~ "Android" and browsers ~ "Chrome"`, false},
+		{`browsers = "Links"`, true},
+		{`browsers = "Link"`, false},
+		{`browsers !~ "Chrome"`, true},
+		{`browsers != "Links"`, false},
+		{`country = "Russia" AND company = "Flashpoint"`, true},
+		{`country = "Russia" AND NOT company = "Flashpoint"`, false},
+		{`country = "Ghana" OR name ~ "Sharon"`, true},
+		{`country = "Ghana" OR name ~ "Sharon" AND email ~ "@gmail"`, false},
+		{`(country = "Ghana" OR name ~ "Sharon") AND email ~ "@muxo"`, true},
+		{`not not job = ""`, true},
+		{`name ~ "\"" OR phone != ""`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if got := f.Match(u); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterSeen(t *testing.T) {
+	u := record(t, &User{Browsers: []string{"Android MSIE", "Android", "Chrome", "MSIE 8", "Firefox"}})
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{DefaultFilter, []string{"Android MSIE", "Android", "MSIE 8"}},
+		{`browsers ~ "Chrome" OR browsers = "Firefox"`, []string{"Chrome", "Firefox"}},
+		{`browsers !~ "Chrome" AND NOT browsers ~ "MSIE"`, []string{}},
+		{`NOT NOT browsers ~ "Fire"`, []string{"Firefox"}},
+		{`name = ""`, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got := []string{}
+			MustCompile(tt.expr).Seen(u, func(b []byte) { got = append(got, string(b)) })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Seen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCompileErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`browsers`,
+		`browsers ~`,
+		`browsers ~ Android`,
+		`browsers ~ "Android`,
+		`browsers ! "Android"`,
+		`age = "10"`,
+		`name = "a" AND`,
+		`(name = "a"`,
+		`name = "a")`,
+		`name = "a" name = "b"`,
+		`name = "a" #`,
+		`name = "\n"`,
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			if f, err := Compile(tt); err == nil {
+				t.Errorf("Compile() = %v, want error", f)
+			}
+		})
+	}
+}
+
+func TestFilterMatchAllocs(t *testing.T) {
+	u := record(t, &User{Browsers: []string{"Android", "MSIE"}, Country: "Russia"})
+	f := MustCompile(DefaultFilter + ` AND country = "Russia"`)
+	seen := func([]byte) {}
+	allocs := testing.AllocsPerRun(100, func() {
+		f.Seen(u, seen)
+		f.Match(u)
+	})
+	if allocs != 0 {
+		t.Errorf("Match() allocs = %v, want 0", allocs)
+	}
+}
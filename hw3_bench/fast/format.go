@@ -0,0 +1,269 @@
+package fast
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+)
+
+// Format is how search results are written
+type Format string
+
+const (
+	// FormatText is the original found users list
+	FormatText Format = "text"
+	// FormatJSON is a single object with users array and summary fields
+	FormatJSON Format = "json"
+	// FormatNDJSON is an object per user line, summary object is the last line
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV is a header and a row per user, summary is the last line starting
+	// with #, csv readers skip it with # as comment character
+	FormatCSV Format = "csv"
+)
+
+// Mask is how emails are shown in results
+type Mask string
+
+const (
+	// MaskAt replaces @ with [at]
+	MaskAt Mask = "at"
+	// MaskRaw keeps email as is
+	MaskRaw Mask = "raw"
+	// MaskRedact hides email completely
+	MaskRedact Mask = "redact"
+	// MaskHash replaces email with hex hmac-sha256 of it with Options.MaskKey,
+	// so users still can be told apart but emails cant be guessed without the key
+	MaskHash Mask = "hash"
+)
+
+const redacted = "[redacted]"
+
+// ErrNoMaskKey is returned for MaskHash without Options.MaskKey
+var ErrNoMaskKey = errors.New("hash mask needs a key")
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatJSON, FormatNDJSON, FormatCSV:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q, want text, json, ndjson or csv", s)
+}
+
+func ParseMask(s string) (Mask, error) {
+	switch m := Mask(s); m {
+	case MaskAt, MaskRaw, MaskRedact, MaskHash:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown mask %q, want at, raw, redact or hash", s)
+}
+
+// check validates options, empty format and mask are the defaults
+func (o *Options) check() error {
+	if o.Offset < 0 || o.Limit < 0 {
+		return fmt.Errorf("negative offset %d or limit %d", o.Offset, o.Limit)
+	}
+	if o.Format != "" {
+		if _, err := ParseFormat(string(o.Format)); err != nil {
+			return err
+		}
+	}
+	if o.Mask != "" {
+		if _, err := ParseMask(string(o.Mask)); err != nil {
+			return err
+		}
+	}
+	if o.Mask == MaskHash && len(o.MaskKey) == 0 {
+		return ErrNoMaskKey
+	}
+	return nil
+}
+
+func newMaskHash(key []byte) hash.Hash {
+	return hmac.New(sha256.New, key)
+}
+
+// appendEmail appends email masked with mask, mac is hmac of MaskHash
+func appendEmail(b, email []byte, mask Mask, mac hash.Hash) []byte {
+	switch mask {
+	case MaskRaw:
+		return append(b, email...)
+	case MaskRedact:
+		return append(b, redacted...)
+	case MaskHash:
+		mac.Reset()
+		mac.Write(email)
+		var sum [sha256.Size]byte
+		var h [2 * sha256.Size]byte
+		hex.Encode(h[:], mac.Sum(sum[:0]))
+		return append(b, h[:]...)
+	}
+	if at := bytes.IndexByte(email, '@'); at >= 0 {
+		b = append(b, email[:at]...)
+		b = append(b, " [at] "...)
+		return append(b, email[at+1:]...)
+	}
+	return append(b, email...)
+}
+
+// appendJSONString appends s quoted, s is valid utf-8 after Extract
+func appendJSONString(b, s []byte) []byte {
+	const hexDigits = "0123456789abcdef"
+	b = append(b, '"')
+	for _, c := range s {
+		switch {
+		case c == '"' || c == '\\':
+			b = append(b, '\\', c)
+		case c < 0x20:
+			b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+		default:
+			b = append(b, c)
+		}
+	}
+	return append(b, '"')
+}
+
+// appendCSV appends field quoted if it needs to be
+func appendCSV(b, s []byte) []byte {
+	if !bytes.ContainsAny(s, ",\"\r\n") && (len(s) == 0 || s[0] != ' ') {
+		return append(b, s...)
+	}
+	b = append(b, '"')
+	for _, c := range s {
+		if c == '"' {
+			b = append(b, '"')
+		}
+		b = append(b, c)
+	}
+	return append(b, '"')
+}
+
+// beginning returns what goes before results
+func (s *searcher) beginning() []byte {
+	b := s.buf[:0]
+	switch s.format {
+	case FormatJSON:
+		b = append(b, `{"users":[`...)
+	case FormatCSV:
+		if s.showInput {
+			b = append(b, "input,"...)
+		}
+		b = append(b, "index,name,email,browsers\n"...)
+	case FormatNDJSON:
+	default:
+		b = append(b, "found users:\n"...)
+	}
+	s.buf = b
+	return b
+}
+
+// result returns found user i as it is written, rec has fields of the user
+// and s.matched has browsers matching filter
+func (s *searcher) result(i int, rec *Record) []byte {
+	b := s.buf[:0]
+	switch s.format {
+	case FormatJSON, FormatNDJSON:
+		if s.format == FormatJSON {
+			// the first result of a chunk has no comma, SearchParallel puts it on merge
+			if s.written > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, '\n')
+		}
+		b = append(b, `{"index":`...)
+		b = strconv.AppendInt(b, int64(i), 10)
+		if s.showInput {
+			b = append(b, `,"input":`...)
+			b = appendJSONString(b, []byte(s.input))
+		}
+		b = append(b, `,"name":`...)
+		b = appendJSONString(b, rec.Name)
+		b = append(b, `,"email":`...)
+		s.scratch = appendEmail(s.scratch[:0], rec.Email, s.mask, s.mac)
+		b = appendJSONString(b, s.scratch)
+		b = append(b, `,"browsers":[`...)
+		for k, br := range s.matched {
+			if k > 0 {
+				b = append(b, ',')
+			}
+			b = appendJSONString(b, br)
+		}
+		b = append(b, "]}"...)
+		if s.format == FormatNDJSON {
+			b = append(b, '\n')
+		}
+	case FormatCSV:
+		if s.showInput {
+			b = appendCSV(b, []byte(s.input))
+			b = append(b, ',')
+		}
+		b = strconv.AppendInt(b, int64(i), 10)
+		b = append(b, ',')
+		b = appendCSV(b, rec.Name)
+		b = append(b, ',')
+		s.scratch = appendEmail(s.scratch[:0], rec.Email, s.mask, s.mac)
+		b = appendCSV(b, s.scratch)
+		b = append(b, ',')
+		// browsers are joined with newlines, user agents have none
+		s.scratch = s.scratch[:0]
+		for k, br := range s.matched {
+			if k > 0 {
+				s.scratch = append(s.scratch, '\n')
+			}
+			s.scratch = append(s.scratch, br...)
+		}
+		b = appendCSV(b, s.scratch)
+		b = append(b, '\n')
+	default:
+		// [i] name <mail [at] host>
+		b = append(b, '[')
+		if s.showInput {
+			b = append(b, s.input...)
+			b = append(b, ':')
+		}
+		b = strconv.AppendInt(b, int64(i), 10)
+		b = append(b, "] "...)
+		b = append(b, rec.Name...)
+		b = append(b, " <"...)
+		b = appendEmail(b, rec.Email, s.mask, s.mac)
+		b = append(b, ">\n"...)
+	}
+	s.buf = b
+	return b
+}
+
+// ending returns what goes after results
+func (s *searcher) ending() []byte {
+	b := s.buf[:0]
+	unique := len(s.seenBrowsers)
+	switch s.format {
+	case FormatJSON:
+		b = append(b, "\n],\"found\":"...)
+		b = strconv.AppendInt(b, int64(s.found), 10)
+		b = append(b, `,"unique_browsers":`...)
+		b = strconv.AppendInt(b, int64(unique), 10)
+		b = append(b, "}\n"...)
+	case FormatNDJSON:
+		b = append(b, `{"summary":{"found":`...)
+		b = strconv.AppendInt(b, int64(s.found), 10)
+		b = append(b, `,"unique_browsers":`...)
+		b = strconv.AppendInt(b, int64(unique), 10)
+		b = append(b, "}}\n"...)
+	case FormatCSV:
+		b = append(b, "# found "...)
+		b = strconv.AppendInt(b, int64(s.found), 10)
+		b = append(b, ", unique browsers "...)
+		b = strconv.AppendInt(b, int64(unique), 10)
+		b = append(b, '\n')
+	default:
+		b = append(b, "\nTotal unique browsers "...)
+		b = strconv.AppendInt(b, int64(unique), 10)
+		b = append(b, '\n')
+	}
+	s.buf = b
+	return b
+}
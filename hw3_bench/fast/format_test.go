@@ -0,0 +1,234 @@
+package fast
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type jsonResult struct {
+	Index    int      `json:"index"`
+	Input    string   `json:"input"`
+	Name     string   `json:"name"`
+	Email    string   `json:"email"`
+	Browsers []string `json:"browsers"`
+}
+
+type jsonSummary struct {
+	Found          int `json:"found"`
+	UniqueBrowsers int `json:"unique_browsers"`
+}
+
+func TestFormats(t *testing.T) {
+	users, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := new(bytes.Buffer)
+	if err := Search(text, users, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	found := strings.Count(text.String(), "\n[")
+
+	outputs := map[Format]string{}
+	for _, f := range []Format{FormatJSON, FormatNDJSON, FormatCSV} {
+		out := new(bytes.Buffer)
+		if err := Search(out, users, Options{Format: f, Mask: MaskRaw}); err != nil {
+			t.Fatal(err)
+		}
+		outputs[f] = out.String()
+
+		// chunks are merged into the same output
+		for _, workers := range []int{1, 3, 16} {
+			got := new(bytes.Buffer)
+			if err := SearchParallel(got, users, Options{Format: f, Mask: MaskRaw}, workers); err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != out.String() {
+				t.Errorf("SearchParallel(%s, %d) results not match\nGot:\n%v\nExpected:\n%v", f, workers, got, out)
+			}
+		}
+	}
+
+	var doc struct {
+		Users []jsonResult `json:"users"`
+		jsonSummary
+	}
+	if err := json.Unmarshal([]byte(outputs[FormatJSON]), &doc); err != nil {
+		t.Fatalf("json output: %v\n%s", err, outputs[FormatJSON])
+	}
+	if len(doc.Users) != found || doc.Found != found || doc.UniqueBrowsers != 114 {
+		t.Errorf("json output has %d users, summary %+v, want %d users and 114 browsers", len(doc.Users), doc.jsonSummary, found)
+	}
+	first := jsonResult{
+		Index: 1, Name: "Susan Ellis", Email: "eum_rerum_explicabo@Topiczoom.info",
+		Browsers: []string{
+			"Mozilla/5.0 (Linux; U; Android 1.5; en-gb; T-Mobile_G2_Touch Build/CUPCAKE) AppleWebKit/528.5  (KHTML, like Gecko) Version/3.1.2 Mobile Safari/525.20.1",
+			"Mozilla/4.0 (compatible; MSIE 7.0; Windows NT 6.0; Trident/5.0)",
+		},
+	}
+	if len(doc.Users) > 0 && !reflect.DeepEqual(doc.Users[0], first) {
+		t.Errorf("json first user = %+v, want %+v", doc.Users[0], first)
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(outputs[FormatNDJSON]))
+	ndjson := []jsonResult{}
+	for sc.Scan() {
+		var v struct {
+			jsonResult
+			Summary *jsonSummary `json:"summary"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &v); err != nil {
+			t.Fatalf("ndjson line: %v\n%s", err, sc.Text())
+		}
+		if v.Summary != nil {
+			if *v.Summary != doc.jsonSummary {
+				t.Errorf("ndjson summary = %+v, want %+v", v.Summary, doc.jsonSummary)
+			}
+			continue
+		}
+		ndjson = append(ndjson, v.jsonResult)
+	}
+	if !reflect.DeepEqual(ndjson, doc.Users) {
+		t.Errorf("ndjson users = %+v, want %+v", ndjson, doc.Users)
+	}
+
+	csvOut := csv.NewReader(strings.NewReader(outputs[FormatCSV]))
+	csvOut.Comment = '#'
+	rows, err := csvOut.ReadAll()
+	if err != nil {
+		t.Fatalf("csv output: %v\n%s", err, outputs[FormatCSV])
+	}
+	if len(rows) != found+1 || !reflect.DeepEqual(rows[0], []string{"index", "name", "email", "browsers"}) {
+		t.Errorf("csv output has %d rows, header %q", len(rows), rows[0])
+	}
+	if want := []string{"1", first.Name, first.Email, strings.Join(first.Browsers, "\n")}; !reflect.DeepEqual(rows[1], want) {
+		t.Errorf("csv first user = %q, want %q", rows[1], want)
+	}
+	if want := fmt.Sprintf("# found %d, unique browsers %d\n", found, doc.UniqueBrowsers); !strings.HasSuffix(outputs[FormatCSV], want) {
+		t.Errorf("csv output doesnt end with summary %q", want)
+	}
+}
+
+func TestFormatEscaping(t *testing.T) {
+	line := `{"name":"Q \"the\" \\, \u0001","email":"a@b","browsers":["MSIE, \"Android\""]}`
+	filter := Options{Filter: MustCompile(`browsers ~ "MSIE"`), FileLines: true}
+
+	out := new(bytes.Buffer)
+	in := []Input{{Name: "x,y.txt", Open: func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(line)), nil
+	}}}
+	filter.Format = FormatNDJSON
+	if err := SearchInputs(out, in, filter); err != nil {
+		t.Fatal(err)
+	}
+	res := jsonResult{}
+	if err := json.Unmarshal(bytes.SplitN(out.Bytes(), []byte("\n"), 2)[0], &res); err != nil {
+		t.Fatalf("ndjson: %v\n%s", err, out)
+	}
+	want := jsonResult{Input: "x,y.txt", Name: "Q \"the\" \\, \x01", Email: "a [at] b", Browsers: []string{`MSIE, "Android"`}}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("ndjson result = %+v, want %+v", res, want)
+	}
+
+	out.Reset()
+	filter.Format = FormatCSV
+	if err := SearchInputs(out, in, filter); err != nil {
+		t.Fatal(err)
+	}
+	r := csv.NewReader(out)
+	r.Comment = '#'
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]string{{"input", "index", "name", "email", "browsers"}, {"x,y.txt", "0", want.Name, "a [at] b", want.Browsers[0]}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("csv = %q, want %q", rows, want)
+	}
+}
+
+func TestMasks(t *testing.T) {
+	tests := []struct {
+		mask Mask
+		key  string
+		want string
+	}{
+		{"", "", "john [at] example.com"},
+		{MaskAt, "", "john [at] example.com"},
+		{MaskRaw, "", "john@example.com"},
+		{MaskRedact, "", "[redacted]"},
+		{MaskHash, "k1", "a3807ffdaf663310ad8322bc03d7fc92a355150e7f66e9566d46336040153c50"},
+		// another key gives another hash
+		{MaskHash, "k2", "0d02ab9aa8e295b97870c0f0769fc366ba51710cd93d043b92d4ede4d04341b1"},
+	}
+	for _, tt := range tests {
+		if got := string(appendEmail(nil, []byte("john@example.com"), tt.mask, newMaskHash([]byte(tt.key)))); got != tt.want {
+			t.Errorf("appendEmail(%q, %q) = %q, want %q", tt.mask, tt.key, got, tt.want)
+		}
+	}
+
+	if _, err := ParseMask("rot13"); err == nil {
+		t.Error("ParseMask() error = nil for unknown mask")
+	}
+	if err := Search(new(bytes.Buffer), nil, Options{Format: "xml"}); err == nil {
+		t.Error("Search() error = nil for unknown format")
+	}
+	if err := Search(new(bytes.Buffer), nil, Options{Mask: MaskHash}); err != ErrNoMaskKey {
+		t.Errorf("Search() error = %v, want %v", err, ErrNoMaskKey)
+	}
+}
+
+func TestPages(t *testing.T) {
+	users, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var all struct {
+		Users []jsonResult `json:"users"`
+		jsonSummary
+	}
+	out := new(bytes.Buffer)
+	if err := Search(out, users, Options{Format: FormatJSON}); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(out.Bytes(), &all); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, limit := range []int{1, 3, 7, 100} {
+		for name, search := range searches {
+			pages := []jsonResult{}
+			for offset := 0; offset <= len(all.Users); offset += limit {
+				var page struct {
+					Users []jsonResult `json:"users"`
+					jsonSummary
+				}
+				out := new(bytes.Buffer)
+				if err := search(out, users, Options{Format: FormatJSON, Offset: offset, Limit: limit}); err != nil {
+					t.Fatal(err)
+				}
+				if err := json.Unmarshal(out.Bytes(), &page); err != nil {
+					t.Fatalf("%s(%d, %d) output: %v\n%s", name, offset, limit, err, out)
+				}
+				if page.jsonSummary != all.jsonSummary {
+					t.Errorf("%s(%d, %d) summary = %+v, want %+v", name, offset, limit, page.jsonSummary, all.jsonSummary)
+				}
+				pages = append(pages, page.Users...)
+			}
+			if !reflect.DeepEqual(pages, all.Users) {
+				t.Errorf("%s pages of %d = %+v, want %+v", name, limit, pages, all.Users)
+			}
+		}
+	}
+
+	if err := Search(io.Discard, users, Options{Offset: -1}); err == nil {
+		t.Errorf("Search(offset -1) error = nil")
+	}
+}
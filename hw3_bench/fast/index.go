@@ -0,0 +1,499 @@
+package fast
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+)
+
+const indexMagic = "hw3idx2\n"
+
+var errIndexFormat = errors.New("bad index file")
+
+// ErrStaleIndex is returned by Check for a file changed after it was indexed
+var ErrStaleIndex = errors.New("index is stale")
+
+// Index is inverted index of users file browsers. It has offsets of every line,
+// dictionary of distinct browsers with lines they are on, and trigrams of
+// browsers to find the ones containing substring without scanning dictionary
+type Index struct {
+	// size and modification time in unix nanoseconds of indexed file,
+	// time is zero if it is not known
+	size    int64
+	modTime int64
+	// offsets of lines, the last one is where the line after the last would start
+	offsets  []int64
+	browsers [][]byte
+	// postings of browsers, sorted line numbers
+	lines    [][]uint32
+	trigrams map[[3]byte][]uint32
+	byName   map[string]uint32
+}
+
+// splitLines is bufio.ScanLines keeping \r, offsets need exact line lengths
+func splitLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// BuildIndex reads users file and indexes it, malformed lines are errors
+func BuildIndex(r io.Reader) (*Index, error) {
+	ix := &Index{trigrams: map[[3]byte][]uint32{}, byName: map[string]uint32{}}
+	rec := &Record{}
+	cr := &countReader{r: r}
+	sc := newScanner(cr)
+	sc.Split(splitLines)
+	var off int64
+	n := uint32(0)
+	for ; sc.Scan(); n++ {
+		line := sc.Bytes()
+		if err := rec.Extract(line, 1<<fieldBrowsers); err != nil {
+			return nil, &LineError{Line: int(n), Err: err}
+		}
+		ix.offsets = append(ix.offsets, off)
+		off += int64(len(line)) + 1
+		for _, b := range rec.Browsers {
+			id, ok := ix.byName[string(b)]
+			if !ok {
+				id = ix.add(b)
+			}
+			// the same browser twice in a user is a single posting
+			if l := ix.lines[id]; len(l) > 0 && l[len(l)-1] == n {
+				continue
+			}
+			ix.lines[id] = append(ix.lines[id], n)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	ix.offsets = append(ix.offsets, off)
+	ix.size = cr.n
+	return ix, nil
+}
+
+// BuildFileIndex is BuildIndex of file that remembers its modification time for Check
+func BuildFileIndex(f fs.File) (*Index, error) {
+	// time is taken before reading, so a change while reading makes index stale
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	ix, err := BuildIndex(f)
+	if err != nil {
+		return nil, err
+	}
+	ix.modTime = st.ModTime().UnixNano()
+	return ix, nil
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// add puts new browser into dictionary
+func (ix *Index) add(b []byte) uint32 {
+	id := uint32(len(ix.browsers))
+	name := string(b)
+	ix.byName[name] = id
+	ix.browsers = append(ix.browsers, []byte(name))
+	ix.lines = append(ix.lines, nil)
+	for i := 0; i+3 <= len(b); i++ {
+		t := [3]byte{b[i], b[i+1], b[i+2]}
+		// ids only grow, so a repeated trigram of the same browser is the last one
+		if p := ix.trigrams[t]; len(p) > 0 && p[len(p)-1] == id {
+			continue
+		}
+		ix.trigrams[t] = append(ix.trigrams[t], id)
+	}
+	return id
+}
+
+// Size is size of indexed file
+func (ix *Index) Size() int64 {
+	return ix.size
+}
+
+// Check returns ErrStaleIndex if file described by st is not the one that was indexed,
+// offsets of a changed file point to wrong lines even if its size is the same
+func (ix *Index) Check(st fs.FileInfo) error {
+	if st.Size() != ix.size {
+		return fmt.Errorf("%w: it was built for %d bytes file, %s is %d bytes", ErrStaleIndex, ix.size, st.Name(), st.Size())
+	}
+	if ix.modTime != 0 && st.ModTime().UnixNano() != ix.modTime {
+		return fmt.Errorf("%w: %s was modified after it was indexed", ErrStaleIndex, st.Name())
+	}
+	return nil
+}
+
+// Lines is number of indexed lines
+func (ix *Index) Lines() int {
+	return len(ix.offsets) - 1
+}
+
+// Browsers is number of distinct browsers
+func (ix *Index) Browsers() int {
+	return len(ix.browsers)
+}
+
+type indexWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (w *indexWriter) uint(v uint64) {
+	n := binary.PutUvarint(w.buf[:], v)
+	w.w.Write(w.buf[:n])
+}
+
+// postings writes sorted numbers as deltas
+func (w *indexWriter) postings(p []uint32) {
+	w.uint(uint64(len(p)))
+	prev := uint32(0)
+	for _, v := range p {
+		w.uint(uint64(v - prev))
+		prev = v
+	}
+}
+
+// WriteTo writes index in compact form, numbers are varints and postings are deltas
+func (ix *Index) WriteTo(out io.Writer) (int64, error) {
+	cw := &countWriter{w: out}
+	w := &indexWriter{w: bufio.NewWriter(cw)}
+	w.w.WriteString(indexMagic)
+	w.uint(uint64(ix.size))
+	w.uint(uint64(ix.modTime))
+
+	w.uint(uint64(ix.Lines()))
+	for i := 1; i < len(ix.offsets); i++ {
+		w.uint(uint64(ix.offsets[i] - ix.offsets[i-1]))
+	}
+
+	w.uint(uint64(len(ix.browsers)))
+	for id, b := range ix.browsers {
+		w.uint(uint64(len(b)))
+		w.w.Write(b)
+		w.postings(ix.lines[id])
+	}
+
+	// trigrams are sorted so the same index gives the same file
+	keys := make([][3]byte, 0, len(ix.trigrams))
+	for t := range ix.trigrams {
+		keys = append(keys, t)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return bytes.Compare(keys[i][:], keys[j][:]) < 0
+	})
+	w.uint(uint64(len(keys)))
+	for _, t := range keys {
+		w.w.Write(t[:])
+		w.postings(ix.trigrams[t])
+	}
+	err := w.w.Flush()
+	return cw.n, err
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+type indexReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (r *indexReader) uint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		r.err = err
+	}
+	return v
+}
+
+// count reads number of items which take at least a byte each in file,
+// slices grow as items are read, so a broken count only ends with EOF
+func (r *indexReader) count() int {
+	n := r.uint()
+	if n > 1<<40 {
+		r.err = errIndexFormat
+		return 0
+	}
+	return int(n)
+}
+
+// bytes reads n bytes, buffer grows with data actually read instead of
+// being allocated for n, which a broken file can set to anything
+func (r *indexReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	b, err := io.ReadAll(io.LimitReader(r.r, int64(n)))
+	switch {
+	case err != nil:
+		r.err = err
+	case len(b) < n:
+		r.err = errIndexFormat
+	}
+	return b
+}
+
+// postings reads numbers written by indexWriter.postings, they must be
+// strictly increasing and less than max
+func (r *indexReader) postings(max int) []uint32 {
+	n := r.count()
+	p := []uint32{}
+	v := uint64(0)
+	for i := 0; i < n && r.err == nil; i++ {
+		d := r.uint()
+		if (i > 0 && d == 0) || d >= uint64(max) || v+d >= uint64(max) {
+			r.err = errIndexFormat
+			break
+		}
+		v += d
+		p = append(p, uint32(v))
+	}
+	return p
+}
+
+// ReadIndex reads index written by WriteTo
+func ReadIndex(in io.Reader) (*Index, error) {
+	r := &indexReader{r: bufio.NewReader(in)}
+	if string(r.bytes(len(indexMagic))) != indexMagic {
+		return nil, errIndexFormat
+	}
+	ix := &Index{trigrams: map[[3]byte][]uint32{}, byName: map[string]uint32{}}
+	ix.size = int64(r.uint())
+	ix.modTime = int64(r.uint())
+
+	n := r.count()
+	off := int64(0)
+	ix.offsets = append(ix.offsets, 0)
+	for i := 0; i < n && r.err == nil; i++ {
+		// every line has a newline, the last one can miss it in file
+		d := r.uint()
+		if d == 0 || d > uint64(ix.size)+1-uint64(off) {
+			r.err = errIndexFormat
+			break
+		}
+		off += int64(d)
+		ix.offsets = append(ix.offsets, off)
+	}
+
+	n = r.count()
+	for i := 0; i < n && r.err == nil; i++ {
+		b := r.bytes(r.count())
+		ix.byName[string(b)] = uint32(i)
+		ix.browsers = append(ix.browsers, b)
+		ix.lines = append(ix.lines, r.postings(ix.Lines()))
+	}
+
+	n = r.count()
+	for i := 0; i < n && r.err == nil; i++ {
+		t := [3]byte{}
+		copy(t[:], r.bytes(3))
+		ix.trigrams[t] = r.postings(len(ix.browsers))
+	}
+
+	if r.err != nil {
+		if r.err == io.EOF || r.err == io.ErrUnexpectedEOF {
+			r.err = errIndexFormat
+		}
+		return nil, fmt.Errorf("read index: %w", r.err)
+	}
+	return ix, nil
+}
+
+// matching returns ids of browsers satisfying c, they are sorted
+func (ix *Index) matching(c *cond) []uint32 {
+	if !c.contains {
+		if id, ok := ix.byName[string(c.value)]; ok {
+			return []uint32{id}
+		}
+		return nil
+	}
+
+	var ids []uint32
+	if len(c.value) < 3 {
+		// too short for trigrams, dictionary is checked whole
+		for id := range ix.browsers {
+			ids = append(ids, uint32(id))
+		}
+	} else {
+		for i := 0; i+3 <= len(c.value); i++ {
+			p := ix.trigrams[[3]byte{c.value[i], c.value[i+1], c.value[i+2]}]
+			if i == 0 {
+				ids = append([]uint32(nil), p...)
+			} else {
+				ids = intersect(ids, p)
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+		}
+	}
+
+	// trigrams can match in wrong order, so every candidate is checked
+	rv := ids[:0]
+	for _, id := range ids {
+		if c.test(ix.browsers[id]) {
+			rv = append(rv, id)
+		}
+	}
+	return rv
+}
+
+// candidates returns sorted lines which can match n, all is set
+// if n cant be narrowed down by browsers postings
+func (ix *Index) candidates(n node) (lines []uint32, all bool) {
+	switch n := n.(type) {
+	case *cond:
+		if n.field != fieldBrowsers || n.negate {
+			return nil, true
+		}
+		for _, id := range ix.matching(n) {
+			lines = union(lines, ix.lines[id])
+		}
+		return lines, false
+	case and:
+		all = true
+		for _, c := range n {
+			l, a := ix.candidates(c)
+			if a {
+				continue
+			}
+			if all {
+				lines, all = l, false
+			} else {
+				lines = intersect(lines, l)
+			}
+		}
+		return lines, all
+	case or:
+		for _, c := range n {
+			l, a := ix.candidates(c)
+			if a {
+				return nil, true
+			}
+			lines = union(lines, l)
+		}
+		return lines, false
+	}
+	return nil, true
+}
+
+func intersect(a, b []uint32) []uint32 {
+	rv := a[:0]
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			rv = append(rv, a[i])
+			i++
+			j++
+		}
+	}
+	return rv
+}
+
+func union(a, b []uint32) []uint32 {
+	rv := make([]uint32, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			rv = append(rv, a[i])
+			i++
+		case a[i] > b[j]:
+			rv = append(rv, b[j])
+			j++
+		default:
+			rv = append(rv, a[i])
+			i++
+			j++
+		}
+	}
+	rv = append(rv, a[i:]...)
+	return append(rv, b[j:]...)
+}
+
+// Search does the same as Search on indexed file src, but only lines which
+// postings say can match are read and parsed. src must be the indexed file
+func (ix *Index) Search(out io.Writer, src io.ReaderAt, opts Options) error {
+	if err := opts.check(); err != nil {
+		return err
+	}
+	s := newSearcher(out, opts)
+	if err := s.begin(); err != nil {
+		return err
+	}
+
+	lines, all := ix.candidates(s.filter.root)
+	next := func(k int) (int, bool) {
+		if all {
+			return k, k < ix.Lines()
+		}
+		if k < len(lines) {
+			return int(lines[k]), true
+		}
+		return 0, false
+	}
+
+	buf := []byte{}
+	for k := 0; ; k++ {
+		i, ok := next(k)
+		if !ok {
+			break
+		}
+		// offsets count a newline after every line
+		start, n := ix.offsets[i], int(ix.offsets[i+1]-ix.offsets[i]-1)
+		if cap(buf) < n {
+			buf = make([]byte, n)
+		}
+		buf = buf[:n]
+		if read, err := src.ReadAt(buf, start); read < n {
+			return fmt.Errorf("line %d: %w", i, err)
+		}
+		if err := s.line(i, buf); err != nil {
+			return err
+		}
+	}
+
+	// unique browsers are counted over the whole file, not candidates only
+	rec := &Record{Browsers: [][]byte{nil}}
+	for _, b := range ix.browsers {
+		rec.Browsers[0] = b
+		s.matched = s.matched[:0]
+		s.filter.Seen(rec, s.seenFunc)
+	}
+	return s.end()
+}
@@ -0,0 +1,227 @@
+package fast
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"localhost/coursera/hw3_bench/gen"
+)
+
+func TestIndexSearch(t *testing.T) {
+	users, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated := new(bytes.Buffer)
+	if err := gen.Write(generated, gen.Config{Seed: 7, Count: 3000}); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"users":     users,
+		"newline":   append(users, '\n'),
+		"crlf":      bytes.ReplaceAll(users, []byte("\n"), []byte("\r\n")),
+		"generated": generated.Bytes(),
+	}
+	filters := []string{
+		DefaultFilter,
+		`browsers ~ "Chrome/5"`,
+		`browsers ~ "Go"`,
+		`browsers ~ "Opera" OR browsers ~ "Lynx"`,
+		`browsers = "curl/7.35.0"`,
+		`browsers = "no such browser"`,
+		`browsers ~ "Firefox" AND (country = "Malta" OR name ~ "Sharon")`,
+		`browsers !~ "Mozilla"`,
+		`NOT browsers ~ "Android"`,
+		`browsers ~ "MSIE" OR name ~ "Susan"`,
+		`country = "Russia"`,
+	}
+
+	for name, data := range files {
+		ix, err := BuildIndex(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: BuildIndex() error = %v", name, err)
+		}
+		if ix.Size() != int64(len(data)) {
+			t.Errorf("%s: Size() = %d, want %d", name, ix.Size(), len(data))
+		}
+
+		// searches go through the written index too
+		file := new(bytes.Buffer)
+		if _, err := ix.WriteTo(file); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadIndex(file)
+		if err != nil {
+			t.Fatalf("%s: ReadIndex() error = %v", name, err)
+		}
+
+		for _, expr := range filters {
+			for _, format := range []Format{FormatText, FormatJSON} {
+				opts := Options{Filter: MustCompile(expr), Format: format}
+				want := new(bytes.Buffer)
+				if err := Search(want, data, opts); err != nil {
+					t.Fatal(err)
+				}
+				got := new(bytes.Buffer)
+				if err := read.Search(got, bytes.NewReader(data), opts); err != nil {
+					t.Errorf("%s: Index.Search(%s, %s) error = %v", name, expr, format, err)
+				}
+				if got.String() != want.String() {
+					t.Errorf("%s: Index.Search(%s, %s) results not match\nGot:\n%v\nExpected:\n%v", name, expr, format, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexCandidates(t *testing.T) {
+	data := `{"browsers":["Mozilla MSIE 8.0","Opera"]}
+{"browsers":["Chrome"]}
+{"browsers":["Opera","Opera"]}
+{"browsers":["IE 8.0"]}`
+	ix, err := BuildIndex(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr  string
+		lines []uint32
+		all   bool
+	}{
+		{`browsers ~ "MSIE"`, []uint32{0}, false},
+		{`browsers ~ "8.0"`, []uint32{0, 3}, false},
+		{`browsers ~ "E 8"`, []uint32{0, 3}, false},
+		{`browsers ~ "0.8"`, nil, false},
+		{`browsers = "Opera"`, []uint32{0, 2}, false},
+		{`browsers ~ "Opera" OR browsers = "Chrome"`, []uint32{0, 1, 2}, false},
+		{`browsers ~ "Opera" AND name = "a"`, []uint32{0, 2}, false},
+		{`browsers ~ "Opera" OR name = "a"`, nil, true},
+		{`browsers !~ "Opera"`, nil, true},
+		{`NOT browsers ~ "Opera"`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			lines, all := ix.candidates(MustCompile(tt.expr).root)
+			// nil and empty lines are the same
+			if all != tt.all || fmt.Sprint(lines) != fmt.Sprint(tt.lines) {
+				t.Errorf("candidates() = %v, %v, want %v, %v", lines, all, tt.lines, tt.all)
+			}
+		})
+	}
+}
+
+func TestIndexStale(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "users.txt")
+	if err := os.WriteFile(fp, []byte(`{"browsers":["Opera"]}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	built, err := BuildFileIndex(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := new(bytes.Buffer)
+	built.WriteTo(file)
+	ix, err := ReadIndex(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func() error {
+		st, err := os.Stat(fp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ix.Check(st)
+	}
+	if err := check(); err != nil {
+		t.Errorf("Check() of indexed file error = %v", err)
+	}
+
+	// the same size, only time tells it is another file
+	if err := os.WriteFile(fp, []byte(`{"browsers":["Lynx!"]}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(fp, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := check(); !errors.Is(err, ErrStaleIndex) {
+		t.Errorf("Check() of edited file error = %v, want %v", err, ErrStaleIndex)
+	}
+
+	if err := os.WriteFile(fp, []byte(`{"browsers":["Opera"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := check(); !errors.Is(err, ErrStaleIndex) {
+		t.Errorf("Check() of truncated file error = %v, want %v", err, ErrStaleIndex)
+	}
+}
+
+// indexFile makes index file with given ints as varints and strings as is
+func indexFile(items ...interface{}) string {
+	b := []byte(indexMagic)
+	buf := [binary.MaxVarintLen64]byte{}
+	for _, v := range items {
+		switch v := v.(type) {
+		case int:
+			b = append(b, buf[:binary.PutUvarint(buf[:], uint64(v))]...)
+		case string:
+			b = append(b, v...)
+		}
+	}
+	return string(b)
+}
+
+func TestIndexErrors(t *testing.T) {
+	_, err := BuildIndex(strings.NewReader("{}\n{broken\n"))
+	lerr := &LineError{}
+	if !errors.As(err, &lerr) || lerr.Line != 1 {
+		t.Errorf("BuildIndex() error = %v, want line 1 error", err)
+	}
+
+	ix, err := BuildIndex(strings.NewReader(`{"browsers":["Opera"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := new(bytes.Buffer)
+	ix.WriteTo(file)
+	broken := []string{
+		"", "hw3idx0\n", file.String()[:file.Len()-1],
+		// browser name longer than file
+		indexFile(0, 0, 0, 1, 1<<39),
+		// empty line, line past end of file
+		indexFile(3, 0, 1, 0),
+		indexFile(3, 0, 1, 5),
+		// line and browser out of range, lines not increasing
+		indexFile(3, 0, 1, 3, 1, 1, "a", 1, 1),
+		indexFile(3, 0, 1, 3, 0, 1, "Ope", 1, 0),
+		indexFile(6, 0, 2, 3, 3, 1, 1, "a", 2, 1, 0),
+	}
+	for _, data := range broken {
+		if _, err := ReadIndex(strings.NewReader(data)); !errors.Is(err, errIndexFormat) {
+			t.Errorf("ReadIndex(%q) error = %v, want %v", data, err, errIndexFormat)
+		}
+	}
+	if _, err := ReadIndex(strings.NewReader(indexFile(6, 0, 2, 3, 3, 1, 1, "a", 2, 0, 1, 0))); err != nil {
+		t.Errorf("ReadIndex() error = %v", err)
+	}
+
+	// file is shorter than index says
+	err = ix.Search(new(bytes.Buffer), strings.NewReader(`{}`), Options{Filter: MustCompile(`browsers = "Opera"`)})
+	if err == nil {
+		t.Errorf("Index.Search() of truncated file error = nil")
+	}
+}
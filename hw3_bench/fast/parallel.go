@@ -0,0 +1,135 @@
+package fast
+
+import (
+	"bytes"
+	"io"
+	"runtime"
+	"sync"
+)
+
+// chunk is a part of users file made of whole lines
+type chunk struct {
+	data []byte
+	// index of the first line
+	first int
+	last  bool
+
+	out       bytes.Buffer
+	seen      map[string]interface{}
+	malformed []*LineError
+	found     int
+	written   int
+	err       error
+}
+
+// lines calls f for every line of chunk with its index in the whole file
+func (c *chunk) lines(f func(i int, line []byte) error) error {
+	data := c.data
+	if len(data) == 0 {
+		return nil
+	}
+	// chunk ends with newline unless it is the end of file, there is no empty
+	// line after it. the same goes for trailing newline of the file
+	if data[len(data)-1] == '\n' {
+		data = data[:len(data)-1]
+	}
+	for i := c.first; ; i++ {
+		n := bytes.IndexByte(data, '\n')
+		if n < 0 {
+			return f(i, data)
+		}
+		if err := f(i, data[:n]); err != nil {
+			return err
+		}
+		data = data[n+1:]
+	}
+}
+
+// splitChunks cuts data into about n chunks right after newlines
+func splitChunks(data []byte, n int) []*chunk {
+	size := len(data)/n + 1
+	rv := make([]*chunk, 0, n)
+	first := 0
+	for {
+		end := len(data)
+		if size < len(data) {
+			if nl := bytes.IndexByte(data[size:], '\n'); nl >= 0 {
+				end = size + nl + 1
+			}
+		}
+		c := &chunk{data: data[:end], first: first, last: end == len(data)}
+		rv = append(rv, c)
+		if c.last {
+			return rv
+		}
+		first += bytes.Count(c.data, []byte("\n"))
+		data = data[end:]
+	}
+}
+
+// SearchParallel does the same as Search, but data is split into chunks at line
+// boundaries which are parsed by workers goroutines (GOMAXPROCS if workers < 1).
+// results of chunks are merged in the original line order
+func SearchParallel(out io.Writer, data []byte, opts Options, workers int) error {
+	if err := opts.check(); err != nil {
+		return err
+	}
+	// a page depends on matches of all lines before it
+	if opts.Offset > 0 || opts.Limit > 0 {
+		return Search(out, data, opts)
+	}
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	// several chunks per worker even out lines of different length
+	chunks := splitChunks(data, workers*4)
+
+	queue := make(chan *chunk, len(chunks))
+	for _, c := range chunks {
+		queue <- c
+	}
+	close(queue)
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for c := range queue {
+				s := newSearcher(&c.out, opts)
+				c.err = c.lines(s.line)
+				c.seen = s.seenBrowsers
+				c.malformed = s.malformed
+				c.found = s.found
+				c.written = s.written
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := newSearcher(out, opts)
+	if err := s.begin(); err != nil {
+		return err
+	}
+	for _, c := range chunks {
+		if c.err != nil {
+			return c.err
+		}
+		// results of chunks are separated the same way results inside them are
+		if s.format == FormatJSON && s.written > 0 && c.written > 0 {
+			if _, err := io.WriteString(out, ","); err != nil {
+				return err
+			}
+		}
+		s.found += c.found
+		s.written += c.written
+		if _, err := c.out.WriteTo(out); err != nil {
+			return err
+		}
+		for b := range c.seen {
+			s.seenBrowsers[b] = nil
+		}
+		s.malformed = append(s.malformed, c.malformed...)
+	}
+	return s.end()
+}
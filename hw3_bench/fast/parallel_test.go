@@ -0,0 +1,52 @@
+package fast
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSearchParallel(t *testing.T) {
+	users, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(users, []byte("\n"))
+	short := bytes.Join(lines[:40], []byte("\n"))
+
+	for _, data := range [][]byte{users, short, lines[0]} {
+		want := new(bytes.Buffer)
+		if err := Search(want, data, Options{}); err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range []int{0, 1, 2, 3, 7, 64} {
+			got := new(bytes.Buffer)
+			if err := SearchParallel(got, data, Options{}, workers); err != nil {
+				t.Fatalf("SearchParallel(%d) error = %v", workers, err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("SearchParallel(%d) results not match\nGot:\n%v\nExpected:\n%v", workers, got, want)
+			}
+		}
+	}
+}
+
+func TestSearchParallelError(t *testing.T) {
+	users, err := os.ReadFile("../data/users.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.Split(users, []byte("\n"))
+	lines[500] = []byte("{broken")
+
+	err = SearchParallel(new(bytes.Buffer), bytes.Join(lines, []byte("\n")), Options{}, 4)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 500:") {
+		t.Errorf("SearchParallel() error = %v, want line 500 error", err)
+	}
+
+	err = SearchParallel(new(bytes.Buffer), append(users, "\n\n"...), Options{}, 4)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 1000:") {
+		t.Errorf("SearchParallel() error = %v, want error for empty line", err)
+	}
+}
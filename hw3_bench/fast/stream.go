@@ -0,0 +1,164 @@
+package fast
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MaxLineSize is the longest line SearchReader accepts
+const MaxLineSize = 1 << 20
+
+// newScanner returns line scanner accepting lines up to MaxLineSize
+func newScanner(r io.Reader) *bufio.Scanner {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), MaxLineSize)
+	return sc
+}
+
+// SearchReader does the same as Search but reads users line by line from r,
+// a single buffer is reused for all lines so memory doesnt depend on input size
+func SearchReader(out io.Writer, r io.Reader, opts Options) error {
+	if err := opts.check(); err != nil {
+		return err
+	}
+	s := newSearcher(out, opts)
+	if err := s.begin(); err != nil {
+		return err
+	}
+	if _, err := s.reader(r, 0); err != nil {
+		return err
+	}
+	return s.end()
+}
+
+// reader searches lines of r numbering them from first, it returns the next number
+func (s *searcher) reader(r io.Reader, first int) (int, error) {
+	sc := newScanner(r)
+	i := first
+	for ; sc.Scan(); i++ {
+		if err := s.line(i, sc.Bytes()); err != nil {
+			return i, err
+		}
+	}
+	return i, sc.Err()
+}
+
+// Input is a named users file opened only when search gets to it
+type Input struct {
+	Name string
+	Open func() (io.ReadCloser, error)
+}
+
+// SearchInputs does SearchReader over inputs one after another as if they were
+// a single file, unless opts.FileLines is set lines are numbered across all of them
+func SearchInputs(out io.Writer, inputs []Input, opts Options) error {
+	if err := opts.check(); err != nil {
+		return err
+	}
+	s := newSearcher(out, opts)
+	if err := s.begin(); err != nil {
+		return err
+	}
+	n := 0
+	for _, in := range inputs {
+		s.input = in.Name
+		if opts.FileLines {
+			n = 0
+		}
+		r, err := in.Open()
+		if err != nil {
+			return err
+		}
+		n, err = s.reader(r, n)
+		cerr := r.Close()
+		if err != nil {
+			var lerr *LineError
+			if !errors.As(err, &lerr) {
+				err = fmt.Errorf("%s: %w", in.Name, err)
+			}
+			return err
+		}
+		if cerr != nil {
+			return fmt.Errorf("%s: %w", in.Name, cerr)
+		}
+	}
+	return s.end()
+}
+
+// Each calls f for every user of r matching opts.Filter, for every user if it is nil.
+// all fields of rec are extracted, it is valid until f returns.
+// malformed lines are handled the same way Search does
+func Each(r io.Reader, opts Options, f func(i int, rec *Record) error) error {
+	sc := newScanner(r)
+	rec := &Record{}
+	malformed := []*LineError{}
+	for i := 0; sc.Scan(); i++ {
+		if err := rec.Extract(sc.Bytes(), allFields); err != nil {
+			lerr := &LineError{Line: i, Err: err}
+			if !opts.Lenient {
+				return lerr
+			}
+			malformed = append(malformed, lerr)
+			continue
+		}
+		if opts.Filter != nil && !opts.Filter.Match(rec) {
+			continue
+		}
+		if err := f(i, rec); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	if len(malformed) > 0 {
+		return &MalformedError{Lines: malformed}
+	}
+	return nil
+}
+
+// ParseRecords extracts all fields of every user of r, record i is line i.
+// malformed lines are errors, records are searched by SearchRecords
+func ParseRecords(r io.Reader) ([]Record, error) {
+	recs := []Record{}
+	err := Each(r, Options{}, func(_ int, rec *Record) error {
+		recs = append(recs, rec.clone())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return recs, nil
+}
+
+// clone copies fields of r into a single buffer, r can be reused then
+func (r *Record) clone() Record {
+	n := len(r.Company) + len(r.Country) + len(r.Email) + len(r.Job) + len(r.Name) + len(r.Phone)
+	for _, b := range r.Browsers {
+		n += len(b)
+	}
+	buf := make([]byte, 0, n)
+	copyField := func(b []byte) []byte {
+		// absent field stays nil
+		if b == nil {
+			return nil
+		}
+		start := len(buf)
+		buf = append(buf, b...)
+		return buf[start:len(buf):len(buf)]
+	}
+
+	rv := Record{Browsers: make([][]byte, len(r.Browsers))}
+	for k, b := range r.Browsers {
+		rv.Browsers[k] = copyField(b)
+	}
+	rv.Company = copyField(r.Company)
+	rv.Country = copyField(r.Country)
+	rv.Email = copyField(r.Email)
+	rv.Job = copyField(r.Job)
+	rv.Name = copyField(r.Name)
+	rv.Phone = copyField(r.Phone)
+	return rv
+}
@@ -0,0 +1,187 @@
+package gen
+
+import (
+	"math/rand"
+	"strconv"
+)
+
+// agent appends a user agent of some kind to b
+type agent func(r *rand.Rand, b []byte) []byte
+
+type weightedAgent struct {
+	weight int
+	agent  agent
+}
+
+var (
+	desktopPlatforms = []string{
+		"Windows NT 10.0; Win64; x64", "Windows NT 6.1; WOW64", "Windows NT 6.1", "Windows NT 5.1",
+		"Windows NT 6.3; Win64; x64", "Macintosh; Intel Mac OS X 10_11_6", "Macintosh; Intel Mac OS X 10_9_5",
+		"X11; Linux x86_64", "X11; Linux i686", "X11; Ubuntu; Linux x86_64", "X11; FreeBSD amd64",
+	}
+	androidDevices = []string{
+		"SM-G900A Build/KOT49H", "Nexus 5 Build/LMY48B", "SPH-L710 Build/JSS15J", "Nexus 7 Build/KTU84P",
+		"SAMSUNG SM-T530NU Build/LRX22G", "HTC One Build/KOT49H", "LG-D855 Build/LRX21R",
+	}
+	androidVersions = []string{"2.3.6", "4.0.4", "4.1.2", "4.3", "4.4.2", "4.4.4", "5.0.2", "5.1.1", "6.0.1"}
+	iosVersions     = []string{"7_1_2", "8_4", "9_2", "9_3_5", "10_2_1"}
+	// old phones and rare browsers are picked as is
+	legacyAgents = []string{
+		"Mozilla/4.0 (compatible; MSIE 6.0; Windows CE; IEMobile 7.11)",
+		"BlackBerry9530/4.7.0.167 Profile/MIDP-2.0 Configuration/CLDC-1.1 VendorID/102 UP.Link/6.3.1.20.0",
+		"SAMSUNG-SGH-A867/A867UCHJ3 SHP/VPP/R5 NetFront/35 SMM-MMS/1.2.0 profile/MIDP-2.0 configuration/CLDC-1.1 UP.Link/6.3.0.0.0",
+		"Nokia6230/2.0 (04.44) Profile/MIDP-2.0 Configuration/CLDC-1.1",
+		"Opera/9.80 (Android; Opera Mini/7.5.33361/31.1543; U; en) Presto/2.8.119 Version/11.1010",
+		"Mozilla/5.0 (compatible; MSIE 9.0; Windows Phone OS 7.5; Trident/5.0; IEMobile/9.0)",
+		"Lynx/2.8.5rel.1 libwww-FM/2.14 SSL-MM/1.4.1 GNUTLS/0.8.12",
+		"Opera/9.30 (Nintendo Wii; U; ; 2047-7; en)",
+	}
+	botAgents = []string{
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+		"Mozilla/5.0 (compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm)",
+		"Mozilla/5.0 (compatible; YandexBot/3.0; +http://yandex.com/bots)",
+		"facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+		"Wget/1.12 (freebsd8.1)",
+		"curl/7.35.0",
+	}
+)
+
+// agents are roughly the mix seen in users.txt
+var agents = []weightedAgent{
+	{25, chrome},
+	{18, firefox},
+	{12, androidChrome},
+	{8, androidBrowser},
+	{9, safari},
+	{7, iosSafari},
+	{8, msie},
+	{4, opera},
+	{5, legacy},
+	{4, bot},
+}
+
+var agentsWeight = func() int {
+	rv := 0
+	for _, a := range agents {
+		rv += a.weight
+	}
+	return rv
+}()
+
+func appendAgent(r *rand.Rand, b []byte) []byte {
+	n := r.Intn(agentsWeight)
+	for _, a := range agents {
+		if n < a.weight {
+			return a.agent(r, b)
+		}
+		n -= a.weight
+	}
+	panic("unreachable")
+}
+
+func pickFrom(r *rand.Rand, s []string) string {
+	return s[r.Intn(len(s))]
+}
+
+// appendChromeVersion appends chrome version, every major release has its own build
+// number and a few patches like the real ones
+func appendChromeVersion(r *rand.Rand, b []byte, min, max int) []byte {
+	major := min + r.Intn(max-min+1)
+	b = strconv.AppendInt(b, int64(major), 10)
+	b = append(b, ".0."...)
+	b = strconv.AppendInt(b, int64(major*71-900), 10)
+	b = append(b, '.')
+	return strconv.AppendInt(b, int64(r.Intn(8)*13), 10)
+}
+
+// appendVersion appends dotted version of parts numbers, the first one is
+// between min and max and the rest are below limits
+func appendVersion(r *rand.Rand, b []byte, min, max int, limits ...int) []byte {
+	b = strconv.AppendInt(b, int64(min+r.Intn(max-min+1)), 10)
+	for _, l := range limits {
+		b = append(b, '.')
+		b = strconv.AppendInt(b, int64(r.Intn(l)), 10)
+	}
+	return b
+}
+
+func chrome(r *rand.Rand, b []byte) []byte {
+	b = append(b, "Mozilla/5.0 ("...)
+	b = append(b, pickFrom(r, desktopPlatforms)...)
+	b = append(b, ") AppleWebKit/537.36 (KHTML, like Gecko) Chrome/"...)
+	b = appendChromeVersion(r, b, 20, 60)
+	return append(b, " Safari/537.36"...)
+}
+
+func firefox(r *rand.Rand, b []byte) []byte {
+	b = append(b, "Mozilla/5.0 ("...)
+	b = append(b, pickFrom(r, desktopPlatforms)...)
+	b = append(b, "; rv:"...)
+	start := len(b)
+	b = appendVersion(r, b, 3, 55, 1)
+	end := len(b)
+	b = append(b, ") Gecko/20100101 Firefox/"...)
+	return append(b, b[start:end]...)
+}
+
+func androidChrome(r *rand.Rand, b []byte) []byte {
+	b = append(b, "Mozilla/5.0 (Linux; Android "...)
+	b = append(b, pickFrom(r, androidVersions)...)
+	b = append(b, "; "...)
+	b = append(b, pickFrom(r, androidDevices)...)
+	b = append(b, ") AppleWebKit/537.36 (KHTML, like Gecko) Chrome/"...)
+	b = appendChromeVersion(r, b, 30, 60)
+	return append(b, " Mobile Safari/537.36"...)
+}
+
+func androidBrowser(r *rand.Rand, b []byte) []byte {
+	b = append(b, "Mozilla/5.0 (Linux; U; Android "...)
+	b = append(b, pickFrom(r, androidVersions)...)
+	b = append(b, "; en-us; "...)
+	b = append(b, pickFrom(r, androidDevices)...)
+	return append(b, ") AppleWebKit/534.30 (KHTML, like Gecko) Version/4.0 Mobile Safari/534.30"...)
+}
+
+func safari(r *rand.Rand, b []byte) []byte {
+	b = append(b, "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_"...)
+	b = appendVersion(r, b, 6, 12, 9)
+	b = append(b, ") AppleWebKit/601.1.56 (KHTML, like Gecko) Version/"...)
+	b = appendVersion(r, b, 5, 10, 3)
+	return append(b, " Safari/601.1.56"...)
+}
+
+func iosSafari(r *rand.Rand, b []byte) []byte {
+	b = append(b, "Mozilla/5.0 ("...)
+	if r.Intn(3) == 0 {
+		b = append(b, "iPad; CPU OS "...)
+	} else {
+		b = append(b, "iPhone; CPU iPhone OS "...)
+	}
+	b = append(b, pickFrom(r, iosVersions)...)
+	b = append(b, " like Mac OS X) AppleWebKit/601.1.46 (KHTML, like Gecko) Version/"...)
+	b = appendVersion(r, b, 7, 10, 3)
+	return append(b, " Mobile/13C75 Safari/601.1"...)
+}
+
+func msie(r *rand.Rand, b []byte) []byte {
+	b = append(b, "Mozilla/4.0 (compatible; MSIE "...)
+	b = appendVersion(r, b, 5, 10, 1)
+	b = append(b, "; "...)
+	b = append(b, pickFrom(r, desktopPlatforms[:5])...)
+	return append(b, ')')
+}
+
+func opera(r *rand.Rand, b []byte) []byte {
+	b = append(b, "Opera/9.80 ("...)
+	b = append(b, pickFrom(r, desktopPlatforms)...)
+	b = append(b, "; U; en) Presto/2.12.388 Version/"...)
+	return appendVersion(r, b, 10, 12, 20)
+}
+
+func legacy(r *rand.Rand, b []byte) []byte {
+	return append(b, pickFrom(r, legacyAgents)...)
+}
+
+func bot(r *rand.Rand, b []byte) []byte {
+	return append(b, pickFrom(r, botAgents)...)
+}
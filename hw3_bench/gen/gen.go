@@ -0,0 +1,158 @@
+package gen
+
+import (
+	"bufio"
+	"io"
+	"math/rand"
+	"strconv"
+)
+
+// Config describes generated users file
+type Config struct {
+	// the same seed gives the same file
+	Seed  int64
+	Count int
+	// BadRate is the share of malformed lines, from 0 to 1
+	BadRate float64
+}
+
+var (
+	firstNames = []string{
+		"Sharon", "Susan", "Jonathan", "Gary", "Irene", "Walter", "Diana", "Henry", "Julia", "Peter",
+		"Ruth", "Carlos", "Emily", "Louis", "Anna", "Victor", "Rebecca", "Joe", "Martha", "Steven",
+	}
+	lastNames = []string{
+		"Crawford", "Ellis", "Morris", "Long", "Gonzales", "Reed", "Fisher", "Hunt", "Black", "Wood",
+		"Price", "Russell", "Howard", "Mills", "Perry", "Cole", "Barnes", "Ward", "Lane", "Stone",
+	}
+	words = []string{
+		"eum", "rerum", "explicabo", "accusamus", "et", "magnam", "sed", "reiciendis", "qui", "quia",
+		"dolor", "amet", "ipsa", "nihil", "vero", "aut", "odit", "sunt", "nemo", "iure",
+	}
+	companies = []string{
+		"Flashpoint", "Jatri", "Dabtype", "Thoughtbeat", "Youfeed", "Feedbug", "Wordware", "Flashspan",
+		"Jazzy", "Jabberbean", "Livetube", "Brainverse", "Photobug", "Avavee", "Roombo", "Topiclounge",
+		"Oloo", "Dynabox", "Yotz", "Avamm", "Topdrive", "Skilith", "Aimbu", "Flipopia", "Skivee",
+	}
+	countries = []string{
+		"Dominican Republic", "Russia", "United States", "Germany", "Brazil", "China", "India", "Malta",
+		"Namibia", "Saint Helena", "France", "Japan", "Canada", "Mexico", "Chad", "Norway", "Peru",
+		"Palestinian Territory, Occupied", "United States Virgin Islands", "Indonesia",
+	}
+	jobs = []string{
+		"Programmer Analyst #{N}", "Web Developer #{N}", "Internal Auditor", "Office Assistant #{N}",
+		"Automation Specialist #{N}", "Cost Accountant", "Electrical Engineer", "Research Assistant #{N}",
+		"Senior Quality Engineer", "Social Worker", "Staff Scientist", "Operator", "Developer #{N}",
+		"Recruiting Manager", "General Manager", "Teacher", "Librarian", "Tax Accountant",
+	}
+	domains = []string{"gov", "info", "biz", "org", "edu", "com", "net", "mil", "name"}
+)
+
+// Write writes cfg.Count users, a JSON object per line in users.txt format
+func Write(w io.Writer, cfg Config) error {
+	bw := bufio.NewWriterSize(w, 256*1024)
+	g := generator{r: rand.New(rand.NewSource(cfg.Seed))}
+	for i := 0; i < cfg.Count; i++ {
+		line := g.user()
+		if cfg.BadRate > 0 && g.r.Float64() < cfg.BadRate {
+			line = g.corrupt(line)
+		}
+		line = append(line, '\n')
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+type generator struct {
+	r   *rand.Rand
+	buf []byte
+}
+
+// user returns next user line, it is valid until the next call
+func (g *generator) user() []byte {
+	r := g.r
+	b := append(g.buf[:0], `{"browsers":[`...)
+	for i, n := 0, 1+r.Intn(6); i < n; i++ {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, '"')
+		b = appendAgent(r, b)
+		b = append(b, '"')
+	}
+
+	company := pickFrom(r, companies)
+	b = append(b, `],"company":`...)
+	b = strconv.AppendQuote(b, company)
+	b = append(b, `,"country":`...)
+	b = strconv.AppendQuote(b, pickFrom(r, countries))
+
+	b = append(b, `,"email":"`...)
+	if r.Intn(2) == 0 {
+		b = append(b, pickFrom(r, firstNames)...)
+		b = append(b, pickFrom(r, lastNames)...)
+	} else {
+		b = append(b, pickFrom(r, words)...)
+		b = append(b, '_')
+		b = append(b, pickFrom(r, words)...)
+	}
+	b = append(b, '@')
+	b = append(b, company...)
+	b = append(b, '.')
+	b = append(b, pickFrom(r, domains)...)
+
+	b = append(b, `","job":`...)
+	b = strconv.AppendQuote(b, pickFrom(r, jobs))
+	b = append(b, `,"name":"`...)
+	b = append(b, pickFrom(r, firstNames)...)
+	b = append(b, ' ')
+	b = append(b, pickFrom(r, lastNames)...)
+
+	b = append(b, `","phone":"`...)
+	if r.Intn(4) == 0 {
+		b = append(b, "8-"...)
+		b = appendNum(b, r.Intn(1000), 3)
+		b = append(b, '-')
+	}
+	b = appendNum(b, r.Intn(1000), 3)
+	b = append(b, '-')
+	b = appendNum(b, r.Intn(100), 2)
+	b = append(b, '-')
+	b = appendNum(b, r.Intn(100), 2)
+	b = append(b, `"}`...)
+	g.buf = b
+	return b
+}
+
+// appendNum appends n padded with zeros to width digits
+func appendNum(b []byte, n, width int) []byte {
+	for w := 10; width > 1; width, w = width-1, w*10 {
+		if n < w {
+			b = append(b, '0')
+		}
+	}
+	return strconv.AppendInt(b, int64(n), 10)
+}
+
+// corrupt breaks line the ways real files get broken
+func (g *generator) corrupt(line []byte) []byte {
+	switch g.r.Intn(4) {
+	case 0:
+		// truncated write
+		return line[:g.r.Intn(len(line))]
+	case 1:
+		// lost quote
+		for i := 1 + g.r.Intn(len(line)-1); i < len(line); i++ {
+			if line[i] == '"' {
+				return append(line[:i], line[i+1:]...)
+			}
+		}
+		return line[:len(line)-1]
+	case 2:
+		// two lines glued together
+		return append(line, line...)
+	}
+	return append(line[:0], "<html><body>502 Bad Gateway</body></html>"...)
+}
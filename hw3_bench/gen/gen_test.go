@@ -0,0 +1,72 @@
+package gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"localhost/coursera/hw3_bench/fast"
+)
+
+func generate(t *testing.T, cfg Config) []byte {
+	out := new(bytes.Buffer)
+	if err := Write(out, cfg); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestWrite(t *testing.T) {
+	data := generate(t, Config{Seed: 1, Count: 5000})
+	if !bytes.Equal(data, generate(t, Config{Seed: 1, Count: 5000})) {
+		t.Error("Write() differs for the same seed")
+	}
+	if bytes.Equal(data, generate(t, Config{Seed: 2, Count: 5000})) {
+		t.Error("Write() is the same for different seeds")
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+	if len(lines) != 5000 {
+		t.Fatalf("Write() wrote %d lines, want 5000", len(lines))
+	}
+	for i, line := range lines {
+		u := fast.User{}
+		if err := json.Unmarshal(line, &u); err != nil {
+			t.Fatalf("line %d: %v\n%s", i, err, line)
+		}
+		if len(u.Browsers) == 0 || u.Name == "" || u.Email == "" || u.Country == "" || u.Phone == "" {
+			t.Fatalf("line %d has empty fields\n%s", i, line)
+		}
+	}
+
+	// default filter should find something like it does in users.txt
+	out := new(bytes.Buffer)
+	if err := fast.Search(out, data, fast.Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if found := bytes.Count(out.Bytes(), []byte("\n[")); found < 10 {
+		t.Errorf("Search() found %d users, want more\n%s", found, out)
+	}
+}
+
+func TestWriteMalformed(t *testing.T) {
+	data := generate(t, Config{Seed: 1, Count: 10000, BadRate: 0.05})
+
+	valid := 0
+	for _, line := range bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n")) {
+		if json.Valid(line) {
+			valid++
+		}
+	}
+	if bad := 10000 - valid; bad < 400 || bad > 600 {
+		t.Errorf("Write() made %d malformed lines, want about 500", bad)
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if err := Write(new(bytes.Buffer), Config{Seed: 1, Count: 1000}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"io"
+	"os"
+	"strings"
+
+	"localhost/coursera/hw3_bench/gen"
+)
+
+// genCmd writes synthetic users file
+func genCmd(args []string) error {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	count := fs.Int("n", 1000, "number of users, from thousands to 100M")
+	seed := fs.Int64("seed", 1, "random seed, the same seed gives the same file")
+	bad := fs.Float64("bad", 0, "share of malformed lines, from 0 to 1")
+	out := fs.String("o", "-", "output file, - is stdout")
+	gz := fs.Bool("gzip", false, "gzip output, on by default for .gz files")
+	fs.Parse(args)
+
+	file := os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		file = f
+		*gz = *gz || strings.HasSuffix(*out, ".gz")
+	}
+
+	var w io.Writer = file
+	var zw *gzip.Writer
+	if *gz {
+		// default level is several times slower than generation itself
+		zw, _ = gzip.NewWriterLevel(file, gzip.BestSpeed)
+		w = zw
+	}
+	if err := gen.Write(w, gen.Config{Seed: *seed, Count: *count, BadRate: *bad}); err != nil {
+		return err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+	if file != os.Stdout {
+		return file.Close()
+	}
+	return nil
+}
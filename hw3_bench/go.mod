@@ -1,8 +1,3 @@
 module localhost/coursera/hw3_bench
 
 go 1.18
-
-require (
-	github.com/josharian/intern v1.0.0 // indirect
-	github.com/mailru/easyjson v0.7.7 // indirect
-)
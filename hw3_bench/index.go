@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"localhost/coursera/hw3_bench/fast"
+)
+
+// indexCmd builds browsers index of users file
+func indexCmd(args []string) error {
+	fs := flag.NewFlagSet("index", flag.ExitOnError)
+	fp := fs.String("file", filePath, "users file, must be plain text, not gzipped")
+	out := fs.String("o", "", "index file, default is users file with .idx suffix")
+	fs.Parse(args)
+	if *out == "" {
+		*out = *fp + ".idx"
+	}
+
+	file, err := os.Open(*fp)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	ix, err := fast.BuildFileIndex(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *fp, err)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	n, err := ix.WriteTo(f)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s: %d lines, %d browsers, %d bytes\n", *out, ix.Lines(), ix.Browsers(), n)
+	return f.Close()
+}
+
+// queryCmd searches users file reading only lines its index points to
+func queryCmd(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	expr := fs.String("filter", fast.DefaultFilter, "filter expression over user fields, browsers conditions use the index")
+	fp := fs.String("file", filePath, "users file")
+	idx := fs.String("index", "", "index file built by index command, default is users file with .idx suffix")
+	format := fs.String("format", "text", "output format: text, json, ndjson or csv")
+	mask := fs.String("mask", "at", "how emails are shown: at replaces @ with [at], raw, redact or hash")
+	maskKey := maskKeyFlag(fs)
+	fs.Parse(args)
+	if *idx == "" {
+		*idx = *fp + ".idx"
+	}
+
+	filter, err := fast.Compile(*expr)
+	if err != nil {
+		return err
+	}
+	opts := fast.Options{Filter: filter, MaskKey: maskKey()}
+	if opts.Format, err = fast.ParseFormat(*format); err != nil {
+		return err
+	}
+	if opts.Mask, err = fast.ParseMask(*mask); err != nil {
+		return err
+	}
+
+	f, err := os.Open(*idx)
+	if err != nil {
+		return err
+	}
+	ix, err := fast.ReadIndex(bufio.NewReader(f))
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", *idx, err)
+	}
+
+	file, err := os.Open(*fp)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	st, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := ix.Check(st); err != nil {
+		return fmt.Errorf("%s: %w", *idx, err)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	err = ix.Search(w, file, opts)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"localhost/coursera/hw3_bench/fast"
+)
+
+// gzipFile closes both decompressor and the file under it
+type gzipFile struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (f gzipFile) Close() error {
+	err := f.Reader.Close()
+	if ferr := f.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// openInput opens users file, .gz files are decompressed and - is stdin
+func openInput(name string) (io.ReadCloser, error) {
+	if name == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return gzipFile{zr, f}, nil
+}
+
+// inputs expands glob patterns into search inputs in the given order,
+// matches of a single pattern are sorted
+func inputs(patterns []string) ([]fast.Input, error) {
+	rv := []fast.Input{}
+	for _, p := range patterns {
+		names := []string{p}
+		if p != "-" && strings.ContainsAny(p, `*?[\`) {
+			var err error
+			if names, err = filepath.Glob(p); err != nil {
+				return nil, fmt.Errorf("%s: %w", p, err)
+			}
+			if len(names) == 0 {
+				return nil, fmt.Errorf("%s: no files match", p)
+			}
+		}
+		for _, name := range names {
+			name := name
+			rv = append(rv, fast.Input{Name: name, Open: func() (io.ReadCloser, error) {
+				return openInput(name)
+			}})
+		}
+	}
+	return rv, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInputs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string, gz bool) {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		var w io.Writer = f
+		if gz {
+			zw := gzip.NewWriter(f)
+			defer zw.Close()
+			w = zw
+		}
+		if _, err := io.WriteString(w, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("b.txt", "b\n", false)
+	write("a.txt", "a\n", false)
+	write("c.gz", "c\n", true)
+
+	in, err := inputs([]string{filepath.Join(dir, "c.gz"), filepath.Join(dir, "*.txt")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, i := range in {
+		r, err := i.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, filepath.Base(i.Name)+" "+string(data))
+	}
+	if want := []string{"c.gz c\n", "a.txt a\n", "b.txt b\n"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("inputs() read %q, want %q", got, want)
+	}
+
+	if _, err := inputs([]string{filepath.Join(dir, "*.json")}); err == nil {
+		t.Error("inputs() error = nil for glob matching nothing")
+	}
+	write("bad.gz", "not gzip", false)
+	if _, err := openInput(filepath.Join(dir, "bad.gz")); err == nil {
+		t.Error("openInput() error = nil for bad gzip")
+	}
+}
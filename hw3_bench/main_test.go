@@ -2,8 +2,15 @@ package main
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"localhost/coursera/hw3_bench/fast"
+	"localhost/coursera/hw3_bench/gen"
 )
 
 // запускаем перед основными функциями по разу чтобы файл остался в памяти в файловом кеше
@@ -30,6 +37,45 @@ func TestSearch(t *testing.T) {
 	}
 }
 
+func TestSearchVariants(t *testing.T) {
+	slowOut := new(bytes.Buffer)
+	SlowSearch(slowOut)
+	slowResult := slowOut.String()
+
+	tests := []struct {
+		name   string
+		search func(out io.Writer)
+	}{
+		{"stream", StreamSearch},
+		{"parallel", ParallelSearch},
+		{"mmap", MmapSearch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := new(bytes.Buffer)
+			tt.search(out)
+			if result := out.String(); slowResult != result {
+				t.Errorf("results not match\nGot:\n%v\nExpected:\n%v", result, slowResult)
+			}
+		})
+	}
+}
+
+func TestMmapSearchEmpty(t *testing.T) {
+	// empty file cant be mapped and is streamed
+	fp := filepath.Join(t.TempDir(), "empty.txt")
+	if err := os.WriteFile(fp, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := mmapSearch(out, fp); err != nil {
+		t.Fatal(err)
+	}
+	if want := "found users:\n\nTotal unique browsers 0\n"; out.String() != want {
+		t.Errorf("mmapSearch(empty) = %q, want %q", out, want)
+	}
+}
+
 // -----
 // go test -bench . -benchmem
 
@@ -50,3 +96,61 @@ func BenchmarkFastDefault(b *testing.B) {
 		FastSearchDefault(ioutil.Discard)
 	}
 }
+
+func BenchmarkStream(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		StreamSearch(ioutil.Discard)
+	}
+}
+
+func BenchmarkParallel(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		ParallelSearch(ioutil.Discard)
+	}
+}
+
+func BenchmarkMmap(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		MmapSearch(ioutil.Discard)
+	}
+}
+
+// BenchmarkLarge compares ways to get generated files of growing size into search,
+// B/op of ReadFile grows with the whole file, of Mmap and Stream with found users only
+func BenchmarkLarge(b *testing.B) {
+	for _, n := range []int{10000, 100000} {
+		fp := filepath.Join(b.TempDir(), "users.txt")
+		f, err := os.Create(fp)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := gen.Write(f, gen.Config{Seed: 1, Count: n}); err != nil {
+			b.Fatal(err)
+		}
+		f.Close()
+
+		b.Run(fmt.Sprint(n, "/ReadFile"), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				data, err := os.ReadFile(fp)
+				if err != nil {
+					b.Fatal(err)
+				}
+				fast.FastSearch(ioutil.Discard, data)
+			}
+		})
+		b.Run(fmt.Sprint(n, "/Mmap"), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := mmapSearch(ioutil.Discard, fp); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprint(n, "/Stream"), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := SearchFile(ioutil.Discard, fp, fast.Options{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
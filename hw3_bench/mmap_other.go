@@ -0,0 +1,16 @@
+//go:build !(linux || darwin)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+func mmap(f *os.File) ([]byte, error) {
+	return nil, errors.New("mmap: not supported")
+}
+
+func munmap(data []byte) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// mmap maps file f read only, data is valid until munmap
+func mmap(f *os.File) ([]byte, error) {
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := st.Size()
+	// empty file cant be mapped, too large one doesnt fit into slice
+	if size == 0 || int64(int(size)) != size {
+		return nil, errors.New("mmap: bad file size")
+	}
+	return syscall.Mmap(int(f.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap(data []byte) error {
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"localhost/coursera/hw3_bench/bench"
+)
+
+// baselineFile is stored in the repo so check runs against committed numbers
+const baselineFile = "./bench_baseline.json"
+
+var errRegression = errors.New("benchmarks regressed")
+
+// baselineCmd runs benchmarks and stores their means as the new baseline
+func baselineCmd(args []string) error {
+	fs := flag.NewFlagSet("baseline", flag.ExitOnError)
+	pattern := fs.String("bench", ".", "benchmarks to run")
+	count := fs.Int("count", 5, "number of runs of every benchmark")
+	in := fs.String("in", "", "read go test -bench output from file (- is stdin) instead of running benchmarks")
+	fp := fs.String("file", baselineFile, "baseline file, thresholds tuned in it are kept")
+	fs.Parse(args)
+
+	samples, err := benchResults(*in, *pattern, *count)
+	if err != nil {
+		return err
+	}
+
+	b, err := bench.LoadBaseline(*fp)
+	if errors.Is(err, os.ErrNotExist) {
+		b, err = &bench.Baseline{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	b.Update(samples)
+	return b.Save(*fp)
+}
+
+// checkCmd runs benchmarks and fails if any of them got worse than baseline allows
+func checkCmd(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	pattern := fs.String("bench", ".", "benchmarks to run")
+	count := fs.Int("count", 5, "number of runs of every benchmark")
+	in := fs.String("in", "", "read go test -bench output from file (- is stdin) instead of running benchmarks")
+	fp := fs.String("file", baselineFile, "baseline file")
+	fs.Parse(args)
+
+	b, err := bench.LoadBaseline(*fp)
+	if err != nil {
+		return err
+	}
+	samples, err := benchResults(*in, *pattern, *count)
+	if err != nil {
+		return err
+	}
+
+	regs, err := b.Check(os.Stdout, samples)
+	if err != nil {
+		return err
+	}
+	if len(regs) == 0 {
+		return nil
+	}
+	fmt.Println()
+	for _, r := range regs {
+		fmt.Println(r)
+	}
+	return fmt.Errorf("%w: %d", errRegression, len(regs))
+}
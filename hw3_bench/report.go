@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"localhost/coursera/hw3_bench/fast"
+	"localhost/coursera/hw3_bench/ua"
+)
+
+// stat counts users and their user agents falling into a single group
+type stat struct {
+	name     string
+	users    int
+	browsers int
+	agents   map[string]bool
+	families map[string]int
+}
+
+// stats are groups by name, e.g. browser families or countries
+type stats map[string]*stat
+
+func (s stats) get(name string) *stat {
+	st, ok := s[name]
+	if !ok {
+		st = &stat{name: name, agents: map[string]bool{}, families: map[string]int{}}
+		s[name] = st
+	}
+	return st
+}
+
+// top returns n groups with most users, all if n < 1
+func (s stats) top(n int) []*stat {
+	rv := make([]*stat, 0, len(s))
+	for _, st := range s {
+		rv = append(rv, st)
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		if rv[i].users != rv[j].users {
+			return rv[i].users > rv[j].users
+		}
+		return rv[i].name < rv[j].name
+	})
+	if n > 0 && len(rv) > n {
+		rv = rv[:n]
+	}
+	return rv
+}
+
+// topFamily is the family most of group agents belong to
+func (st *stat) topFamily() string {
+	rv, max := "", 0
+	for f, n := range st.families {
+		if n > max || (n == max && f < rv) {
+			rv, max = f, n
+		}
+	}
+	return rv
+}
+
+// Report is browsers statistics of users file
+type Report struct {
+	parser   ua.Parser
+	users    int
+	browsers int
+	agents   map[string]bool
+
+	families  stats
+	oses      stats
+	devices   stats
+	countries stats
+	companies stats
+}
+
+func NewReport() *Report {
+	return &Report{
+		agents:    map[string]bool{},
+		families:  stats{},
+		oses:      stats{},
+		devices:   stats{},
+		countries: stats{},
+		companies: stats{},
+	}
+}
+
+// Add counts user record
+func (r *Report) Add(rec *fast.Record) {
+	r.users++
+	// groups this user was counted in
+	counted := map[*stat]bool{}
+	add := func(st *stat, agent string, a ua.Agent) {
+		if !counted[st] {
+			counted[st] = true
+			st.users++
+		}
+		st.browsers++
+		st.agents[agent] = true
+		st.families[a.Family]++
+	}
+
+	country, company := r.countries.get(string(rec.Country)), r.companies.get(string(rec.Company))
+	if len(rec.Browsers) == 0 {
+		country.users++
+		company.users++
+	}
+	for _, b := range rec.Browsers {
+		a := r.parser.Parse(b)
+		agent := string(b)
+		r.browsers++
+		r.agents[agent] = true
+		add(r.families.get(a.Family), agent, a)
+		add(r.oses.get(a.OS), agent, a)
+		add(r.devices.get(a.Device), agent, a)
+		add(country, agent, a)
+		add(company, agent, a)
+	}
+}
+
+// WriteTo writes report tables with top n rows each
+func (r *Report) WriteTo(w io.Writer, n int) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "users\t%d\nbrowsers\t%d\nunique user agents\t%d\n", r.users, r.browsers, len(r.agents))
+
+	for _, t := range []struct {
+		title string
+		s     stats
+	}{
+		{"family", r.families},
+		{"os", r.oses},
+		{"device", r.devices},
+	} {
+		fmt.Fprintf(tw, "\n%s\tusers\tbrowsers\tshare\tunique agents\n", t.title)
+		for _, st := range t.s.top(n) {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f%%\t%d\n", st.name, st.users, st.browsers,
+				100*float64(st.browsers)/float64(r.browsers), len(st.agents))
+		}
+	}
+
+	for _, t := range []struct {
+		title string
+		s     stats
+	}{
+		{"country", r.countries},
+		{"company", r.companies},
+	} {
+		fmt.Fprintf(tw, "\n%s\tusers\tbrowsers\tunique agents\ttop family\n", t.title)
+		for _, st := range t.s.top(n) {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", st.name, st.users, st.browsers, len(st.agents), st.topFamily())
+		}
+	}
+	return tw.Flush()
+}
+
+// reportCmd prints browsers statistics of users matching filter
+func reportCmd(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	expr := fs.String("filter", "", "filter expression over user fields, all users by default")
+	fp := fs.String("file", filePath, "users file")
+	n := fs.Int("top", 10, "rows in every table, 0 is all")
+	lenient := fs.Bool("lenient", false, "skip malformed lines instead of stopping")
+	fs.Parse(args)
+
+	opts := fast.Options{Lenient: *lenient}
+	if *expr != "" {
+		filter, err := fast.Compile(*expr)
+		if err != nil {
+			return err
+		}
+		opts.Filter = filter
+	}
+
+	file, err := os.Open(*fp)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := NewReport()
+	err = fast.Each(file, opts, func(_ int, rec *fast.Record) error {
+		r.Add(rec)
+		return nil
+	})
+	merr := &fast.MalformedError{}
+	if errors.As(err, &merr) {
+		fmt.Fprintf(os.Stderr, "skipped %d malformed lines\n", len(merr.Lines))
+	} else if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if err := r.WriteTo(w, *n); err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"localhost/coursera/hw3_bench/fast"
+)
+
+func TestReport(t *testing.T) {
+	data := `{"company":"Foo","country":"Malta","browsers":["Mozilla/5.0 (X11; Linux i686; rv:49.0) Gecko/20100101 Firefox/49.0","Mozilla/5.0 (X11; Linux i686; rv:48.0) Gecko/20100101 Firefox/48.0"]}
+{"company":"Foo","country":"Chad","browsers":["Mozilla/4.0 (compatible; MSIE 8.0; Windows NT 6.1; Trident/4.0)","Mozilla/5.0 (X11; Linux i686; rv:49.0) Gecko/20100101 Firefox/49.0"]}
+{"company":"Bar","country":"Malta"}`
+
+	r := NewReport()
+	err := fast.Each(strings.NewReader(data), fast.Options{}, func(_ int, rec *fast.Record) error {
+		r.Add(rec)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := new(bytes.Buffer)
+	if err := r.WriteTo(out, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range [][]string{
+		{"users", "3"},
+		{"browsers", "4"},
+		{"unique", "user", "agents", "3"},
+		// family users browsers share unique
+		{"Firefox", "2", "3", "75.0%", "2"},
+		{"IE", "1", "1", "25.0%", "1"},
+		{"Linux", "2", "3", "75.0%", "2"},
+		{"Windows", "7", "1", "1", "25.0%", "1"},
+		// country users browsers unique top
+		{"Malta", "2", "2", "2", "Firefox"},
+		{"Chad", "1", "2", "2", "Firefox"},
+		{"Foo", "2", "4", "3", "Firefox"},
+		{"Bar", "1", "0", "0"},
+	} {
+		if !hasRow(out.String(), row) {
+			t.Errorf("report has no row %q\n%s", row, out)
+		}
+	}
+}
+
+func hasRow(report string, row []string) bool {
+	for _, line := range strings.Split(report, "\n") {
+		if strings.Join(strings.Fields(line), " ") == strings.Join(row, " ") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"localhost/coursera/hw3_bench/fast"
+)
+
+// maskKeyEnv is environment variable with the default key of hash mask
+const maskKeyEnv = "HW3_MASK_KEY"
+
+// maskKeyFlag defines -mask-key flag, key is taken from environment if flag is
+// empty, so it isnt seen in process list or usage. the returned func gives key
+// after flags are parsed
+func maskKeyFlag(fs *flag.FlagSet) func() []byte {
+	key := fs.String("mask-key", "", "key of hash mask, emails are hmac-sha256 with it, $"+maskKeyEnv+" is used if empty")
+	return func() []byte {
+		if *key == "" {
+			return []byte(os.Getenv(maskKeyEnv))
+		}
+		return []byte(*key)
+	}
+}
+
+// searchCmd searches users files given as arguments or by -file with filter given by flags
+func searchCmd(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	expr := fs.String("filter", fast.DefaultFilter, "filter expression over user fields, e.g. "+`'country = "Russia" AND browsers ~ "MSIE"'`)
+	fp := fs.String("file", filePath, "users file, used if no files are given as arguments")
+	lenient := fs.Bool("lenient", false, "skip malformed lines and report them at the end instead of stopping")
+	lines := fs.String("lines", "global", "line numbering, global across files or file to start every file from zero")
+	format := fs.String("format", "text", "output format: text, json, ndjson or csv")
+	mask := fs.String("mask", "at", "how emails are shown: at replaces @ with [at], raw, redact or hash")
+	maskKey := maskKeyFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: search [flags] [file or glob ...]\n\n.gz files are decompressed, - is stdin\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *lines != "global" && *lines != "file" {
+		return fmt.Errorf("bad -lines %q, want global or file", *lines)
+	}
+	filter, err := fast.Compile(*expr)
+	if err != nil {
+		return err
+	}
+	opts := fast.Options{Filter: filter, Lenient: *lenient, FileLines: *lines == "file", MaskKey: maskKey()}
+	if opts.Format, err = fast.ParseFormat(*format); err != nil {
+		return err
+	}
+	if opts.Mask, err = fast.ParseMask(*mask); err != nil {
+		return err
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{*fp}
+	}
+	in, err := inputs(patterns)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	err = fast.SearchInputs(w, in, opts)
+	if ferr := w.Flush(); err == nil {
+		err = ferr
+	}
+
+	merr := &fast.MalformedError{}
+	if errors.As(err, &merr) {
+		fmt.Fprintf(os.Stderr, "skipped %d malformed lines:\n", len(merr.Lines))
+		for _, l := range merr.Lines {
+			fmt.Fprintf(os.Stderr, "  %v\n", l)
+		}
+		return nil
+	}
+	return err
+}
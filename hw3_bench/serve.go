@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"localhost/coursera/hw3_bench/fast"
+)
+
+// defaultLimit is page size of searches without limit parameter
+const defaultLimit = 100
+
+var contentTypes = map[fast.Format]string{
+	fast.FormatText:   "text/plain; charset=utf-8",
+	fast.FormatJSON:   "application/json",
+	fast.FormatNDJSON: "application/x-ndjson",
+	fast.FormatCSV:    "text/csv; charset=utf-8",
+}
+
+// searchServer serves searches over users file parsed once
+type searchServer struct {
+	recs []fast.Record
+	// key of hash mask, the mask is not allowed without it
+	maskKey []byte
+	// stats of all users, filtered ones are counted per request
+	stats *Report
+	mux   *http.ServeMux
+}
+
+// newSearchServer checks users file data, it has to have no malformed lines
+func newSearchServer(data, maskKey []byte) (*searchServer, error) {
+	recs, err := fast.ParseRecords(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	s := &searchServer{recs: recs, maskKey: maskKey, stats: NewReport(), mux: http.NewServeMux()}
+	for i := range recs {
+		s.stats.Add(&recs[i])
+	}
+	s.mux.HandleFunc("/search", s.search)
+	s.mux.HandleFunc("/stats", s.statsHandler)
+	return s, nil
+}
+
+func (s *searchServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", contentTypes[fast.FormatJSON])
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// intParam returns non negative query parameter or def if it is not set
+func intParam(q url.Values, name string, def int) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("bad %s %q, want non negative number", name, v)
+	}
+	return n, nil
+}
+
+// searchOptions reads search parameters: filter, format, mask, offset and limit
+func searchOptions(q url.Values, maskKey []byte) (fast.Options, error) {
+	opts := fast.Options{MaskKey: maskKey}
+	var err error
+
+	expr := q.Get("filter")
+	if expr == "" {
+		expr = fast.DefaultFilter
+	}
+	if opts.Filter, err = fast.Compile(expr); err != nil {
+		return opts, err
+	}
+
+	format, mask := q.Get("format"), q.Get("mask")
+	if format == "" {
+		format = string(fast.FormatJSON)
+	}
+	if mask == "" {
+		mask = string(fast.MaskAt)
+	}
+	if opts.Format, err = fast.ParseFormat(format); err != nil {
+		return opts, err
+	}
+	if opts.Mask, err = fast.ParseMask(mask); err != nil {
+		return opts, err
+	}
+	if opts.Mask == fast.MaskHash && len(maskKey) == 0 {
+		return opts, fast.ErrNoMaskKey
+	}
+
+	if opts.Offset, err = intParam(q, "offset", 0); err != nil {
+		return opts, err
+	}
+	// limit=0 gives all users after offset, they are streamed as found
+	if opts.Limit, err = intParam(q, "limit", defaultLimit); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// search writes page of users matching filter, found in summary counts all of them
+func (s *searchServer) search(w http.ResponseWriter, r *http.Request) {
+	opts, err := searchOptions(r.URL.Query(), s.maskKey)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// results go to client as they are found, response isnt kept in memory
+	w.Header().Set("Content-Type", contentTypes[opts.Format])
+	if err := fast.SearchRecords(w, s.recs, opts); err != nil {
+		// status is sent already, client sees truncated response
+		log.Printf("search %s: %v", r.URL.RawQuery, err)
+	}
+}
+
+type statRow struct {
+	Name         string  `json:"name"`
+	Users        int     `json:"users"`
+	Browsers     int     `json:"browsers"`
+	Share        float64 `json:"share"`
+	UniqueAgents int     `json:"unique_agents"`
+}
+
+type statsResponse struct {
+	Users        int       `json:"users"`
+	Browsers     int       `json:"browsers"`
+	UniqueAgents int       `json:"unique_agents"`
+	Families     []statRow `json:"families"`
+	OS           []statRow `json:"os"`
+	Devices      []statRow `json:"devices"`
+}
+
+func statRows(st stats, n, browsers int) []statRow {
+	rv := []statRow{}
+	for _, s := range st.top(n) {
+		row := statRow{Name: s.name, Users: s.users, Browsers: s.browsers, UniqueAgents: len(s.agents)}
+		if browsers > 0 {
+			row.Share = float64(s.browsers) / float64(browsers)
+		}
+		rv = append(rv, row)
+	}
+	return rv
+}
+
+// statsHandler writes browser counts of users matching filter, of all users by default
+func (s *searchServer) statsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	n, err := intParam(q, "top", 10)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rep := s.stats
+	if expr := q.Get("filter"); expr != "" {
+		filter, err := fast.Compile(expr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		rep = NewReport()
+		for i := range s.recs {
+			if filter.Match(&s.recs[i]) {
+				rep.Add(&s.recs[i])
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", contentTypes[fast.FormatJSON])
+	json.NewEncoder(w).Encode(&statsResponse{
+		Users:        rep.users,
+		Browsers:     rep.browsers,
+		UniqueAgents: len(rep.agents),
+		Families:     statRows(rep.families, n, rep.browsers),
+		OS:           statRows(rep.oses, n, rep.browsers),
+		Devices:      statRows(rep.devices, n, rep.browsers),
+	})
+}
+
+// serveCmd serves searches over users file
+func serveCmd(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	fp := fs.String("file", filePath, "users file, it is parsed once at start")
+	maskKey := maskKeyFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `usage: serve [flags]
+
+endpoints:
+  /search?filter=&format=json&mask=at&offset=0&limit=%d  users matching filter, limit=0 is all of them
+  /stats?filter=&top=10                                    browser families, os and devices counts
+
+`, defaultLimit)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	data, err := os.ReadFile(*fp)
+	if err != nil {
+		return err
+	}
+	s, err := newSearchServer(data, maskKey())
+	if err != nil {
+		return fmt.Errorf("%s: %w", *fp, err)
+	}
+	log.Printf("serving %s with %d users on %s", *fp, len(s.recs), *addr)
+	return http.ListenAndServe(*addr, s)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"localhost/coursera/hw3_bench/fast"
+)
+
+// testMaskKey is hash mask key of test server
+var testMaskKey = []byte("test")
+
+func newTestServer(t *testing.T, maskKey []byte) (*httptest.Server, []byte) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := newSearchServer(data, maskKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts, data
+}
+
+func get(t *testing.T, u string) (int, string) {
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestServeSearch(t *testing.T) {
+	ts, data := newTestServer(t, testMaskKey)
+
+	tests := []struct {
+		query string
+		opts  fast.Options
+	}{
+		{"", fast.Options{Format: fast.FormatJSON, Limit: defaultLimit}},
+		{"format=text&limit=0", fast.Options{}},
+		{"format=ndjson&mask=hash&offset=2&limit=3", fast.Options{Format: fast.FormatNDJSON, Mask: fast.MaskHash, MaskKey: testMaskKey, Offset: 2, Limit: 3}},
+		{"format=csv&offset=1000", fast.Options{Format: fast.FormatCSV, Offset: 1000}},
+		{
+			"filter=" + url.QueryEscape(`browsers ~ "Opera" AND country = "Russia"`) + "&limit=5",
+			fast.Options{Filter: fast.MustCompile(`browsers ~ "Opera" AND country = "Russia"`), Format: fast.FormatJSON, Limit: 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			want := new(bytes.Buffer)
+			if err := fast.Search(want, data, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			code, got := get(t, ts.URL+"/search?"+tt.query)
+			if code != http.StatusOK || got != want.String() {
+				t.Errorf("/search = %d\n%s\nwant 200\n%s", code, got, want)
+			}
+		})
+	}
+}
+
+func TestServeErrors(t *testing.T) {
+	ts, _ := newTestServer(t, testMaskKey)
+
+	for _, path := range []string{
+		"/search?filter=" + url.QueryEscape(`browsers ~`),
+		"/search?format=xml",
+		"/search?mask=none",
+		"/search?offset=-1",
+		"/search?limit=ten",
+		"/stats?top=-1",
+		"/stats?filter=name",
+	} {
+		code, body := get(t, ts.URL+path)
+		var resp struct{ Error string }
+		if code != http.StatusBadRequest || json.Unmarshal([]byte(body), &resp) != nil || resp.Error == "" {
+			t.Errorf("%s = %d %s, want 400 with error", path, code, body)
+		}
+	}
+
+	nokey, _ := newTestServer(t, nil)
+	if code, _ := get(t, nokey.URL+"/search?mask=hash"); code != http.StatusBadRequest {
+		t.Errorf("/search?mask=hash without key = %d, want 400", code)
+	}
+
+	resp, err := http.Post(ts.URL+"/search", "text/plain", strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /search = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestServeStats(t *testing.T) {
+	ts, _ := newTestServer(t, testMaskKey)
+
+	stats := func(query string) *statsResponse {
+		code, body := get(t, ts.URL+"/stats?"+query)
+		rv := &statsResponse{}
+		if code != http.StatusOK || json.Unmarshal([]byte(body), rv) != nil {
+			t.Fatalf("/stats?%s = %d %s", query, code, body)
+		}
+		return rv
+	}
+
+	all := stats("")
+	if all.Users != 1000 || len(all.Families) != 10 || all.Families[0].Users < all.Families[1].Users {
+		t.Errorf("/stats = %+v, want 1000 users and top 10 families", all)
+	}
+
+	ie := stats("top=0&filter=" + url.QueryEscape(`browsers ~ "MSIE"`))
+	if ie.Users == 0 || ie.Users >= all.Users || ie.Browsers >= all.Browsers {
+		t.Errorf("/stats?filter = %+v, want part of %d users", ie, all.Users)
+	}
+	hasIE := false
+	for _, f := range ie.Families {
+		hasIE = hasIE || f.Name == "IE"
+	}
+	if !hasIE {
+		t.Errorf("/stats?filter families = %+v, want IE", ie.Families)
+	}
+}
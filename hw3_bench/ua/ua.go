@@ -0,0 +1,269 @@
+package ua
+
+import (
+	"strings"
+	"sync"
+)
+
+// device classes
+const (
+	Desktop = "desktop"
+	Mobile  = "mobile"
+	Tablet  = "tablet"
+	Console = "console"
+	Bot     = "bot"
+	Other   = "other"
+)
+
+// Agent is what user agent string tells about the client
+type Agent struct {
+	Family  string
+	Version string
+	OS      string
+	Device  string
+}
+
+// familyRule maps token found in user agent to browser family,
+// version follows token or version token if it is set
+type familyRule struct {
+	token   string
+	family  string
+	version string
+}
+
+// familyRules are checked in order, so browsers built on top of others
+// go before the ones they mimic, e.g. Maxthon says it is Chrome and Safari too
+var familyRules = []familyRule{
+	{"Opera Mini/", "Opera Mini", ""},
+	{"OPR/", "Opera", ""},
+	{"Opera", "Opera", "Version/"},
+	{"Edge/", "Edge", ""},
+	{"IEMobile", "IE Mobile", ""},
+	{"MSIE ", "IE", ""},
+	{"Trident/", "IE", "rv:"},
+	{"SeaMonkey/", "SeaMonkey", ""},
+	{"Iceape/", "SeaMonkey", ""},
+	{"Iceweasel/", "Iceweasel", ""},
+	{"Fennec/", "Fennec", ""},
+	{"Camino/", "Camino", ""},
+	{"Galeon/", "Galeon", ""},
+	{"Epiphany/", "Epiphany", ""},
+	{"Firebird/", "Firebird", ""},
+	{"Flock/", "Flock", ""},
+	{"K-Meleon/", "K-Meleon", ""},
+	{"Netscape", "Netscape", ""},
+	{"Konqueror/", "Konqueror", ""},
+	{"Maxthon", "Maxthon", ""},
+	{"QupZilla/", "QupZilla", ""},
+	{"Arora/", "Arora", ""},
+	{"Midori/", "Midori", ""},
+	{"Puffin/", "Puffin", ""},
+	{"YaBrowser/", "Yandex Browser", ""},
+	{"UCBrowser/", "UC Browser", ""},
+	{"NokiaBrowser/", "Nokia Browser", ""},
+	{"Silk/", "Silk", ""},
+	{"UCWEB", "UC Browser", ""},
+	{"OmniWeb/", "OmniWeb", ""},
+	{"BrowserNG/", "Nokia Browser", ""},
+	{"SEMC-Browser/", "SEMC Browser", ""},
+	{"UP.Browser/", "Openwave", ""},
+	{"Obigo", "Obigo", ""},
+	{"POLARIS/", "Polaris", ""},
+	{"NetSurf/", "NetSurf", ""},
+	{"Avant Browser", "Avant", ""},
+	{"iTunes/", "iTunes", ""},
+	{"Chromium/", "Chromium", ""},
+	{"CriOS/", "Chrome", ""},
+	{"Chrome/", "Chrome", ""},
+	{"FxiOS/", "Firefox", ""},
+	{"Firefox/", "Firefox", ""},
+	{"NetFront", "NetFront", ""},
+	{"Dillo", "Dillo", ""},
+	{"Lynx/", "Lynx", ""},
+	{"Links", "Links", ""},
+	{"w3m/", "w3m", ""},
+	{"EudoraWeb", "EudoraWeb", ""},
+	{"BlackBerry", "BlackBerry", "Version/"},
+	{"BB10", "BlackBerry", "Version/"},
+	{"PlayBook", "BlackBerry", "Version/"},
+	{"Android", "Android Browser", "Version/"},
+	{"Safari", "Safari", "Version/"},
+	{"Gecko/", "Mozilla", "rv:"},
+	{"AppleWebKit/", "WebKit", ""},
+}
+
+// botTokens are lowercase parts of crawler and tool user agents
+var botTokens = []string{
+	"bot", "crawl", "spider", "slurp", "facebookexternalhit", "validator",
+	"htmlparser", "wget", "curl", "libwww-perl", "python-", "java/", "httpclient",
+}
+
+type osRule struct {
+	token string
+	os    string
+}
+
+var osRules = []osRule{
+	{"Windows Phone", "Windows Phone"},
+	{"Windows CE", "Windows CE"},
+	{"Windows NT 10.0", "Windows 10"},
+	{"Windows NT 6.3", "Windows 8.1"},
+	{"Windows NT 6.2", "Windows 8"},
+	{"Windows NT 6.1", "Windows 7"},
+	{"Windows NT 6.0", "Windows Vista"},
+	{"Windows NT 5.2", "Windows XP"},
+	{"Windows NT 5.1", "Windows XP"},
+	{"Windows XP", "Windows XP"},
+	{"Windows NT 5.0", "Windows 2000"},
+	{"Win", "Windows"},
+	{"iPhone", "iOS"},
+	{"iPad", "iOS"},
+	{"iPod", "iOS"},
+	{"Mac OS X", "Mac OS X"},
+	{"Macintosh", "Mac OS"},
+	{"Android", "Android"},
+	{"CrOS", "Chrome OS"},
+	{"BlackBerry", "BlackBerry"},
+	{"BB10", "BlackBerry"},
+	{"RIM Tablet OS", "BlackBerry"},
+	{"Symbian", "Symbian"},
+	{"Series60", "Symbian"},
+	{"PalmOS", "Palm OS"},
+	{"webOS", "Palm OS"},
+	{"FreeBSD", "FreeBSD"},
+	{"freebsd", "FreeBSD"},
+	{"NetBSD", "NetBSD"},
+	{"OpenBSD", "OpenBSD"},
+	{"SunOS", "Solaris"},
+	{"OS/2", "OS/2"},
+	{"Kindle", "Kindle"},
+	{"PlayStation", "PlayStation"},
+	{"PLAYSTATION", "PlayStation"},
+	{"BeOS", "BeOS"},
+	{"Nintendo", "Nintendo"},
+	{"wii", "Nintendo"},
+	{"Xbox", "Xbox"},
+	{"Linux", "Linux"},
+	{"X11", "Unix"},
+}
+
+var (
+	tabletTokens  = []string{"iPad", "Tablet", "PlayBook", "Kindle", "Silk/"}
+	consoleTokens = []string{"PlayStation", "PLAYSTATION", "Nintendo", "wii", "Xbox"}
+	mobileTokens  = []string{
+		"Mobile", "iPhone", "iPod", "Android", "Opera Mini", "BlackBerry", "BB10",
+		"Symbian", "Series60", "MIDP", "Windows CE", "Windows Phone", "PalmOS",
+		"webOS", "NetFront", "DoCoMo", "UP.Browser", "UP.Link", "SAMSUNG-", "SonyEricsson", "Nokia",
+	}
+	desktopOS = map[string]bool{
+		"Windows 10": true, "Windows 8.1": true, "Windows 8": true, "Windows 7": true,
+		"Windows Vista": true, "Windows XP": true, "Windows 2000": true, "Windows": true,
+		"Mac OS X": true, "Mac OS": true, "Chrome OS": true, "Linux": true, "Unix": true,
+		"FreeBSD": true, "NetBSD": true, "OpenBSD": true, "Solaris": true, "OS/2": true,
+	}
+)
+
+// Parse classifies user agent string, parts it knows nothing about are Other.
+// it is rule based and catches common browsers only, not every one in the wild
+func Parse(s string) Agent {
+	a := Agent{Family: Other, OS: Other, Device: Other}
+
+	for _, r := range osRules {
+		if strings.Contains(s, r.token) {
+			a.OS = r.os
+			break
+		}
+	}
+
+	lower := strings.ToLower(s)
+	for _, t := range botTokens {
+		if strings.Contains(lower, t) {
+			a.Family, a.Device = "Bot", Bot
+			return a
+		}
+	}
+
+	for _, r := range familyRules {
+		i := strings.Index(s, r.token)
+		if i < 0 {
+			continue
+		}
+		a.Family = r.family
+		if r.version != "" {
+			a.Version = version(s, r.version)
+		}
+		if a.Version == "" {
+			a.Version = version(s[i:], r.token)
+		}
+		break
+	}
+
+	a.Device = device(s, a)
+	return a
+}
+
+// version returns version number following token
+func version(s, token string) string {
+	i := strings.Index(s, token)
+	if i < 0 {
+		return ""
+	}
+	s = strings.TrimLeft(s[i+len(token):], " /")
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return !(r == '.' || r == '_' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z'))
+	})
+	if end >= 0 {
+		s = s[:end]
+	}
+	// version is expected to start with a digit, names are not versions
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return ""
+	}
+	return s
+}
+
+func containsAny(s string, tokens []string) bool {
+	for _, t := range tokens {
+		if strings.Contains(s, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func device(s string, a Agent) string {
+	switch {
+	case containsAny(s, consoleTokens):
+		return Console
+	case containsAny(s, tabletTokens):
+		return Tablet
+	// android tablets dont say they are mobile
+	case a.OS == "Android" && !strings.Contains(s, "Mobile") && !strings.Contains(s, "Opera Mini"):
+		return Tablet
+	case containsAny(s, mobileTokens):
+		return Mobile
+	case desktopOS[a.OS]:
+		return Desktop
+	}
+	return Other
+}
+
+// Parser is Parse remembering results, the same agents repeat a lot
+type Parser struct {
+	mu    sync.Mutex
+	cache map[string]Agent
+}
+
+func (p *Parser) Parse(s []byte) Agent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if a, ok := p.cache[string(s)]; ok {
+		return a
+	}
+	if p.cache == nil {
+		p.cache = map[string]Agent{}
+	}
+	a := Parse(string(s))
+	p.cache[string(s)] = a
+	return a
+}
@@ -0,0 +1,106 @@
+package ua
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want Agent
+	}{
+		{
+			"Mozilla/5.0 (Windows NT 5.1) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/36.0.1985.67 Safari/537.36",
+			Agent{"Chrome", "36.0.1985.67", "Windows XP", Desktop},
+		},
+		{
+			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/534.24 (KHTML, like Gecko) Ubuntu/10.10 Chromium/12.0.703.0 Chrome/12.0.703.0 Safari/534.24",
+			Agent{"Chromium", "12.0.703.0", "Linux", Desktop},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 6.2; Win64; x64; rv:16.0) Gecko/16.0 Firefox/16.0",
+			Agent{"Firefox", "16.0", "Windows 8", Desktop},
+		},
+		{
+			"Mozilla/4.0 (compatible; MSIE 8.0; Windows NT 6.1; Trident/4.0)",
+			Agent{"IE", "8.0", "Windows 7", Desktop},
+		},
+		{
+			"Mozilla/4.0 (compatible; MSIE 6.0; Windows CE; IEMobile 7.11)",
+			Agent{"IE Mobile", "7.11", "Windows CE", Mobile},
+		},
+		{
+			"Opera/9.80 (X11; FreeBSD 8.1-RELEASE i386; Edition Next) Presto/2.12.388 Version/12.10",
+			Agent{"Opera", "12.10", "FreeBSD", Desktop},
+		},
+		{
+			"Opera/9.80 (Android; Opera Mini/7.5.33361/31.1543; U; en) Presto/2.8.119 Version/11.1010",
+			Agent{"Opera Mini", "7.5.33361", "Android", Mobile},
+		},
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 9_2 like Mac OS X) AppleWebKit/601.1.46 (KHTML, like Gecko) Version/9.0 Mobile/13C75 Safari/601.1",
+			Agent{"Safari", "9.0", "iOS", Mobile},
+		},
+		{
+			"Mozilla/5.0 (Linux; U; Android 2.2; en-us; SCH-I800 Build/FROYO) AppleWebKit/533.1 (KHTML, like Gecko) Version/4.0 Mobile Safari/533.1",
+			Agent{"Android Browser", "4.0", "Android", Mobile},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 4.4.4; Nexus 7 Build/KTU84P) AppleWebKit/537.36 (KHTML like Gecko) Chrome/36.0.1985.135 Safari/537.36",
+			Agent{"Chrome", "36.0.1985.135", "Android", Tablet},
+		},
+		{
+			"Mozilla/5.0 (PlayBook; U; RIM Tablet OS 2.1.0; en-US) AppleWebKit/536.2+ (KHTML like Gecko) Version/7.2.1.0 Safari/536.2+",
+			Agent{"BlackBerry", "7.2.1.0", "BlackBerry", Tablet},
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_10_5) AppleWebKit/600.8.9 (KHTML, like Gecko) Maxthon/4.5.2",
+			Agent{"Maxthon", "4.5.2", "Mac OS X", Desktop},
+		},
+		{
+			"Opera/9.30 (Nintendo Wii; U; ; 2047-7; en)",
+			Agent{"Opera", "9.30", "Nintendo", Console},
+		},
+		{
+			"Mozilla/5.0 (compatible; Googlebot/2.1;  http://www.google.com/bot.html)",
+			Agent{"Bot", "", Other, Bot},
+		},
+		{
+			"Wget/1.12 (freebsd8.1)",
+			Agent{"Bot", "", "FreeBSD", Bot},
+		},
+		{
+			"ELinks (0.4.3; NetBSD 3.0.2PATCH sparc64; 141x19)",
+			Agent{"Links", "", "NetBSD", Desktop},
+		},
+		{
+			"Nokia6230/2.0 (04.44) Profile/MIDP-2.0 Configuration/CLDC-1.1",
+			Agent{Other, "", Other, Mobile},
+		},
+		{
+			"Mediapartners-Google",
+			Agent{Other, "", Other, Other},
+		},
+		{
+			"",
+			Agent{Other, "", Other, Other},
+		},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.ua); got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.ua, got, tt.want)
+		}
+	}
+}
+
+func TestParser(t *testing.T) {
+	p := Parser{}
+	ua := []byte("Mozilla/5.0 (X11; Linux i686; rv:49.0) Gecko/20100101 Firefox/49.0")
+	want := Parse(string(ua))
+	for i := 0; i < 2; i++ {
+		if got := p.Parse(ua); got != want {
+			t.Errorf("Parser.Parse() = %+v, want %+v", got, want)
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() { p.Parse(ua) }); allocs != 0 {
+		t.Errorf("cached Parser.Parse() allocs = %v, want 0", allocs)
+	}
+}
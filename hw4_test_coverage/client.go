@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -50,10 +51,22 @@ const (
 type SearchRequest struct {
 	Limit      int
 	Offset     int    // Можно учесть после сортировки
-	Query      string // подстрока в 1 из полей
+	Query      string // слова как подстроки слов Name и About без учета регистра, см. parseQuery
 	OrderField string
 	// -1 по убыванию, 0 как встретилось, 1 по возрастанию
 	OrderBy int
+
+	// фильтры вместе с Query, нулевые значения не фильтруют
+	Gender string // male или female
+	AgeMin int
+	AgeMax int
+	IdIn   []int
+}
+
+// SearchCursorResponse is a page of search by cursor, NextCursor is empty on the last one
+type SearchCursorResponse struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"next_cursor"`
 }
 
 type SearchClient struct {
@@ -86,7 +99,53 @@ func (srv *SearchClient) FindUsers(req SearchRequest) (*SearchResponse, error) {
 	searcherParams.Add("query", req.Query)
 	searcherParams.Add("order_field", req.OrderField)
 	searcherParams.Add("order_by", strconv.Itoa(req.OrderBy))
+	addFilters(searcherParams, &req)
+
+	body, err := srv.get(searcherParams, req.OrderField)
+	if err != nil {
+		return nil, err
+	}
+
+	data := []User{}
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return nil, fmt.Errorf("cant unpack result json: %s", err)
+	}
+
+	result := SearchResponse{}
+	if len(data) == req.Limit {
+		result.NextPage = true
+		result.Users = data[0 : len(data)-1]
+	} else {
+		result.Users = data[0:len(data)]
+	}
+
+	return &result, err
+}
+
+// addFilters adds only filters which are set, so servers without them still work
+// for requests without filters
+func addFilters(searcherParams url.Values, req *SearchRequest) {
+	if req.Gender != "" {
+		searcherParams.Add("gender", req.Gender)
+	}
+	if req.AgeMin != 0 {
+		searcherParams.Add("age_min", strconv.Itoa(req.AgeMin))
+	}
+	if req.AgeMax != 0 {
+		searcherParams.Add("age_max", strconv.Itoa(req.AgeMax))
+	}
+	if len(req.IdIn) > 0 {
+		ids := make([]string, 0, len(req.IdIn))
+		for _, id := range req.IdIn {
+			ids = append(ids, strconv.Itoa(id))
+		}
+		searcherParams.Add("id_in", strings.Join(ids, ","))
+	}
+}
 
+// get sends search request and returns body of successful response
+func (srv *SearchClient) get(searcherParams url.Values, orderField string) ([]byte, error) {
 	searcherReq, err := http.NewRequest("GET", srv.URL+"?"+searcherParams.Encode(), nil)
 	searcherReq.Header.Add("AccessToken", srv.AccessToken)
 
@@ -112,24 +171,76 @@ func (srv *SearchClient) FindUsers(req SearchRequest) (*SearchResponse, error) {
 			return nil, fmt.Errorf("cant unpack error json: %s", err)
 		}
 		if errResp.Error == "ErrorBadOrderField" {
-			return nil, fmt.Errorf("OrderFeld %s invalid", req.OrderField)
+			return nil, fmt.Errorf("OrderFeld %s invalid", orderField)
 		}
 		return nil, fmt.Errorf("unknown bad request error: %s", errResp.Error)
 	}
 
-	data := []User{}
-	err = json.Unmarshal(body, &data)
-	if err != nil {
-		return nil, fmt.Errorf("cant unpack result json: %s", err)
+	return body, nil
+}
+
+// UserPages walks every page of a search with cursors:
+//
+//	pages := srv.Pages(req)
+//	for pages.Next() {
+//		use(pages.Users())
+//	}
+//	err := pages.Err()
+type UserPages struct {
+	srv *SearchClient
+	req SearchRequest
+	// cursor of the next page, empty for the first one
+	cursor string
+	page   []User
+	done   bool
+	err    error
+}
+
+// Pages returns iterator over all users found by req, pages are req.Limit users
+// long, 25 if it is not set. req.Offset is ignored
+func (srv *SearchClient) Pages(req SearchRequest) *UserPages {
+	if req.Limit <= 0 || req.Limit > 25 {
+		req.Limit = 25
 	}
+	return &UserPages{srv: srv, req: req}
+}
 
-	result := SearchResponse{}
-	if len(data) == req.Limit {
-		result.NextPage = true
-		result.Users = data[0 : len(data)-1]
-	} else {
-		result.Users = data[0:len(data)]
+// Next gets the next page, it returns false after the last one or on error
+func (p *UserPages) Next() bool {
+	if p.done || p.err != nil {
+		return false
 	}
 
-	return &result, err
+	searcherParams := url.Values{}
+	searcherParams.Add("limit", strconv.Itoa(p.req.Limit))
+	searcherParams.Add("cursor", p.cursor)
+	searcherParams.Add("query", p.req.Query)
+	searcherParams.Add("order_field", p.req.OrderField)
+	searcherParams.Add("order_by", strconv.Itoa(p.req.OrderBy))
+	addFilters(searcherParams, &p.req)
+
+	body, err := p.srv.get(searcherParams, p.req.OrderField)
+	if err != nil {
+		p.err = err
+		return false
+	}
+	resp := SearchCursorResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		p.err = fmt.Errorf("cant unpack result json: %s", err)
+		return false
+	}
+
+	p.page = resp.Users
+	p.cursor = resp.NextCursor
+	p.done = resp.NextCursor == ""
+	return true
+}
+
+// Users is the current page
+func (p *UserPages) Users() []User {
+	return p.page
+}
+
+func (p *UserPages) Err() error {
+	return p.err
 }
@@ -64,6 +64,7 @@ func TestSearchClient(t *testing.T) {
 		{"4", AccessToken, s.URL, SearchRequest{OrderField: "any"}, fmt.Errorf("OrderFeld %s invalid", "any")},
 		{"5", AccessToken, s.URL, SearchRequest{OrderBy: 2}, fmt.Errorf("unknown bad request error: %s", ErrorInvalidOrderby.Error())},
 		{"6", AccessToken, s.URL, SearchRequest{Query: "Boyd", Limit: 30}, nil},
+		{"7", AccessToken, s.URL, SearchRequest{OrderField: "Age,Foo"}, fmt.Errorf("OrderFeld %s invalid", "Age,Foo")},
 	}
 	for _, tt := range tests {
 		t.Run(tt.n, func(t *testing.T) {
@@ -120,12 +121,15 @@ func TestSearchClientUsers(t *testing.T) {
 		u []User
 	}{
 		{"1", SearchRequest{Limit: 4, OrderField: "Id", OrderBy: OrderByDesc}, []User{ss.users[0], ss.users[1], ss.users[2], ss.users[3]}},
-		{"2", SearchRequest{Limit: 4, OrderField: "Id", OrderBy: OrderByAsc}, []User{ss.users[4], ss.users[3], ss.users[2], ss.users[1]}},
-		{"3", SearchRequest{Limit: 4, OrderField: "Age", OrderBy: OrderByDesc}, []User{ss.users[1], ss.users[0], ss.users[2], ss.users[3]}},
-		{"4", SearchRequest{Limit: 4, OrderField: "Age", OrderBy: OrderByAsc}, []User{ss.users[4], ss.users[3], ss.users[2], ss.users[0]}},
-		{"5", SearchRequest{Limit: 4, OrderField: "Name", OrderBy: OrderByDesc}, []User{ss.users[0], ss.users[2], ss.users[3], ss.users[1]}},
-		{"6", SearchRequest{Limit: 4, OrderField: "Name", OrderBy: OrderByAsc}, []User{ss.users[4], ss.users[1], ss.users[3], ss.users[2]}},
-		{"7", SearchRequest{Limit: 4, OrderField: "", OrderBy: OrderByAsc}, []User{ss.users[4], ss.users[1], ss.users[3], ss.users[2]}},
+		{"2", SearchRequest{Limit: 4, OrderField: "Id", OrderBy: OrderByAsc}, []User{ss.users[34], ss.users[33], ss.users[32], ss.users[31]}},
+		{"3", SearchRequest{Limit: 4, OrderField: "Age", OrderBy: OrderByDesc}, []User{ss.users[1], ss.users[15], ss.users[23], ss.users[0]}},
+		{"4", SearchRequest{Limit: 4, OrderField: "Age", OrderBy: OrderByAsc}, []User{ss.users[13], ss.users[32], ss.users[6], ss.users[26]}},
+		{"5", SearchRequest{Limit: 4, OrderField: "Name", OrderBy: OrderByDesc}, []User{ss.users[15], ss.users[16], ss.users[19], ss.users[22]}},
+		{"6", SearchRequest{Limit: 4, OrderField: "Name", OrderBy: OrderByAsc}, []User{ss.users[13], ss.users[33], ss.users[18], ss.users[26]}},
+		{"7", SearchRequest{Limit: 4, OrderField: "", OrderBy: OrderByAsc}, []User{ss.users[13], ss.users[33], ss.users[18], ss.users[26]}},
+		{"8", SearchRequest{Offset: 30, Limit: 4, OrderField: "Id", OrderBy: OrderByDesc}, []User{ss.users[30], ss.users[31], ss.users[32], ss.users[33]}},
+		{"9", SearchRequest{Limit: 4, Gender: "female", AgeMin: 30, AgeMax: 35}, []User{ss.users[5], ss.users[7], ss.users[16], ss.users[22]}},
+		{"10", SearchRequest{Limit: 4, IdIn: []int{20, 2}, OrderField: "Id", OrderBy: OrderByAsc}, []User{ss.users[20], ss.users[2]}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.n, func(t *testing.T) {
@@ -139,3 +143,114 @@ func TestSearchClientUsers(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchClientNextPage(t *testing.T) {
+	ss, e := NewServer(AccessToken, "dataset.xml")
+	assert.NotNil(t, ss)
+	assert.NoError(t, e)
+
+	s := httptest.NewServer(ss)
+	defer s.Close()
+
+	tests := []struct {
+		n    string
+		r    SearchRequest
+		len  int
+		next bool
+	}{
+		{"first", SearchRequest{Limit: 10, OrderField: "Id"}, 10, true},
+		{"middle", SearchRequest{Offset: 20, Limit: 10, OrderField: "Id"}, 10, true},
+		{"last partial", SearchRequest{Offset: 30, Limit: 10, OrderField: "Id"}, 5, false},
+		{"last full", SearchRequest{Offset: 30, Limit: 5, OrderField: "Id"}, 5, false},
+		{"one before last", SearchRequest{Offset: 29, Limit: 5, OrderField: "Id"}, 5, true},
+		{"empty", SearchRequest{Offset: 35, Limit: 5, OrderField: "Id"}, 0, false},
+		{"query", SearchRequest{Limit: 1, Query: "Guerr"}, 1, true},
+		{"query last", SearchRequest{Offset: 1, Limit: 1, Query: "Guerr"}, 1, false},
+		// limit is capped by 25 on both sides, the user to look ahead is still there
+		{"max limit", SearchRequest{Limit: 25, OrderField: "Id"}, 25, true},
+		{"over max limit", SearchRequest{Limit: 30, OrderField: "Id"}, 25, true},
+		{"max limit last", SearchRequest{Offset: 10, Limit: 25, OrderField: "Id"}, 25, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.n, func(t *testing.T) {
+			srv := &SearchClient{
+				AccessToken: AccessToken,
+				URL:         s.URL,
+			}
+			r, err := srv.FindUsers(tt.r)
+			assert.Nil(t, err)
+			assert.Len(t, r.Users, tt.len)
+			assert.Equal(t, tt.next, r.NextPage)
+		})
+	}
+
+	// pages walked one by one give every user once
+	srv := &SearchClient{AccessToken: AccessToken, URL: s.URL}
+	seen := []User{}
+	for r, offset := (&SearchResponse{NextPage: true}), 0; r.NextPage; offset += 7 {
+		var err error
+		r, err = srv.FindUsers(SearchRequest{Offset: offset, Limit: 7, OrderField: "Age", OrderBy: OrderByDesc})
+		assert.NoError(t, err)
+		seen = append(seen, r.Users...)
+	}
+	assert.ElementsMatch(t, ss.users, seen)
+}
+
+func TestSearchClientPages(t *testing.T) {
+	ss, e := NewServer(AccessToken, "dataset.xml")
+	assert.NotNil(t, ss)
+	assert.NoError(t, e)
+
+	s := httptest.NewServer(ss)
+	defer s.Close()
+
+	srv := &SearchClient{AccessToken: AccessToken, URL: s.URL}
+	tests := []struct {
+		n     string
+		r     SearchRequest
+		pages int
+	}{
+		{"as is", SearchRequest{Limit: 10}, 4},
+		{"age", SearchRequest{Limit: 7, OrderField: "Age", OrderBy: OrderByAsc}, 5},
+		{"name", SearchRequest{OrderField: "Name", OrderBy: OrderByDesc}, 2},
+		{"query", SearchRequest{Limit: 1, Query: "Guerr"}, 2},
+		{"several keys", SearchRequest{Limit: 4, OrderField: "Age,-Name", OrderBy: OrderByDesc}, 9},
+		{"filters", SearchRequest{Limit: 2, Gender: "male", AgeMin: 30, IdIn: []int{4, 6, 10, 12, 14}}, 2},
+		{"ranked", SearchRequest{Limit: 3, Query: "nulla OR velit"}, 9},
+		{"nothing", SearchRequest{Query: "no such user"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.n, func(t *testing.T) {
+			// the same users offset pagination gives
+			want := []User{}
+			for offset, next := 0, true; next; offset += 10 {
+				req := tt.r
+				req.Offset, req.Limit = offset, 10
+				r, err := srv.FindUsers(req)
+				assert.NoError(t, err)
+				want, next = append(want, r.Users...), r.NextPage
+			}
+
+			got, pages := []User{}, 0
+			it := srv.Pages(tt.r)
+			for it.Next() {
+				got = append(got, it.Users()...)
+				pages++
+			}
+			assert.NoError(t, it.Err())
+			assert.Equal(t, ids(want), ids(got))
+			assert.Equal(t, tt.pages, pages)
+		})
+	}
+
+	it := (&SearchClient{AccessToken: AccessToken + "?", URL: s.URL}).Pages(SearchRequest{})
+	assert.False(t, it.Next())
+	assert.False(t, it.Next())
+	assert.Equal(t, fmt.Errorf("Bad AccessToken"), it.Err())
+
+	st := httptest.NewServer(&stubServer{f: stubBrokenJsonOk})
+	defer st.Close()
+	it = (&SearchClient{AccessToken: AccessToken, URL: st.URL}).Pages(SearchRequest{})
+	assert.False(t, it.Next())
+	assert.Error(t, it.Err())
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// cursor is the last user of a page, the next page starts right after it.
+// it has every field users are sorted by and hash of request it was made for,
+// so cursor of one query, order or filters cant be used with another
+type cursor struct {
+	Id    int     `json:"id"`
+	Age   int     `json:"age"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score,omitempty"`
+	Req   string  `json:"req"`
+}
+
+func requestHash(sc *SearchRequest) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%q %q %d %q %d %d %v",
+		sc.Query, sc.OrderField, sc.OrderBy, sc.Gender, sc.AgeMin, sc.AgeMax, sc.IdIn)))
+	return hex.EncodeToString(h[:8])
+}
+
+func (s *server) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// encodeCursor returns cursor pointing after u with relevance score,
+// it is json and its hmac in base64
+func (s *server) encodeCursor(u User, score float64, sc *SearchRequest) string {
+	payload, _ := json.Marshal(cursor{Id: u.Id, Age: u.Age, Name: u.Name, Score: score, Req: requestHash(sc)})
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(s.sign(payload))
+}
+
+// decodeCursor checks cursor of sc and returns user it points after and its score
+func (s *server) decodeCursor(sc *request) (*User, float64, error) {
+	enc := base64.RawURLEncoding
+	p, sig, ok := strings.Cut(sc.cursor, ".")
+	if !ok {
+		return nil, 0, errors.Wrap(ErrorBadCursor, "no signature")
+	}
+	payload, err := enc.DecodeString(p)
+	if err != nil {
+		return nil, 0, errors.Wrap(ErrorBadCursor, "bad encoding")
+	}
+	mac, err := enc.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.sign(payload)) {
+		return nil, 0, errors.Wrap(ErrorBadCursor, "bad signature")
+	}
+
+	c := cursor{}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, 0, errors.Wrap(ErrorBadCursor, "bad payload")
+	}
+	if c.Req != requestHash(&sc.SearchRequest) {
+		return nil, 0, errors.Wrap(ErrorBadCursor, "cursor of another request")
+	}
+	return &User{Id: c.Id, Age: c.Age, Name: c.Name}, c.Score, nil
+}
+
+// searchByCursor returns page of found users after cursor and cursor of the next page.
+// users are found after the cursor user even if it is gone since, so pages dont
+// shift when data changes
+func (s *server) searchByCursor(u []User, scores map[int]float64, sc *request) ([]byte, int) {
+	less := order(&sc.SearchRequest, scores)
+	// cursor needs total order, as is order is Id order then
+	if less == nil {
+		less = func(a, b User) bool { return a.Id < b.Id }
+	}
+	u = sortUsers(u, less)
+
+	start := 0
+	if sc.cursor != "" {
+		after, score, err := s.decodeCursor(sc)
+		if err != nil {
+			js, _ := json.Marshal(SearchErrorResponse{Error: errors.Cause(err).Error()})
+			return js, http.StatusBadRequest
+		}
+		if scores != nil {
+			scores[after.Id] = score
+		}
+		start = sort.Search(len(u), func(i int) bool { return less(*after, u[i]) })
+	}
+
+	rv := SearchCursorResponse{Users: page(u, start, sc.Limit)}
+	if n := len(rv.Users); n > 0 && start+n < len(u) {
+		last := rv.Users[n-1]
+		rv.NextCursor = s.encodeCursor(last, scores[last.Id], &sc.SearchRequest)
+	}
+
+	js, _ := json.Marshal(rv)
+
+	return js, http.StatusOK
+}
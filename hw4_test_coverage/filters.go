@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// intParam parses non negative parameter, it is 0 if not set
+func intParam(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, errors.Wrap(ErrorStrIntCast, name)
+	}
+	if n < 0 {
+		return 0, errors.Wrap(ErrorWrongValue, name+" should be positive")
+	}
+	return n, nil
+}
+
+// parseFilters reads gender, age_min, age_max and id_in, unset ones dont filter
+func parseFilters(q url.Values, sr *SearchRequest) (err error) {
+	sr.Gender = q.Get("gender")
+	switch sr.Gender {
+	case "", "male", "female":
+	default:
+		return errors.Wrap(ErrorWrongValue, "gender should be male or female")
+	}
+
+	if sr.AgeMin, err = intParam(q, "age_min"); err != nil {
+		return err
+	}
+	if sr.AgeMax, err = intParam(q, "age_max"); err != nil {
+		return err
+	}
+	if sr.AgeMax > 0 && sr.AgeMin > sr.AgeMax {
+		return errors.Wrap(ErrorWrongValue, "age_min is greater than age_max")
+	}
+
+	if v := q.Get("id_in"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			id, err := strconv.Atoi(s)
+			if err != nil {
+				return errors.Wrap(ErrorStrIntCast, "id_in")
+			}
+			sr.IdIn = append(sr.IdIn, id)
+		}
+	}
+	return nil
+}
+
+// match tells if u passes filters of sr, query is searched in index
+func (sr *SearchRequest) match(u *User) bool {
+	if sr.Gender != "" && u.Gender != sr.Gender {
+		return false
+	}
+	if u.Age < sr.AgeMin || (sr.AgeMax > 0 && u.Age > sr.AgeMax) {
+		return false
+	}
+	if len(sr.IdIn) == 0 {
+		return true
+	}
+	for _, id := range sr.IdIn {
+		if u.Id == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// bm25 parameters, the usual ones
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// index is inverted index of users Name and About tokens
+type index struct {
+	// positions of term in users, by user index. About positions go after
+	// Name ones with a gap, so phrase cant start in one and end in another
+	terms map[string]map[int][]int
+	// sorted terms, query words are looked up in them as substrings
+	vocab []string
+	// tokens in user
+	length    []int
+	avgLength float64
+}
+
+// fold maps rune to the same one for all its cases, e.g. K, k and Kelvin sign.
+// runes strings.EqualFold treats as equal are folded to the same one: it is
+// the lower case of the smallest rune of unicode.SimpleFold orbit, or that rune
+// itself if its lower case is out of orbit
+func fold(r rune) rune {
+	min := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		if f < min {
+			min = f
+		}
+	}
+	// İ is lower cased to i, but they arent equal folds
+	l := unicode.ToLower(min)
+	for f := unicode.SimpleFold(min); f != min; f = unicode.SimpleFold(f) {
+		if f == l {
+			return l
+		}
+	}
+	return min
+}
+
+// tokenize splits s into case folded words of letters and digits
+func tokenize(s string) []string {
+	rv := []string{}
+	for _, w := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		rv = append(rv, strings.Map(fold, w))
+	}
+	return rv
+}
+
+func newIndex(users []User) *index {
+	ix := &index{terms: map[string]map[int][]int{}, length: make([]int, len(users))}
+	total := 0
+	for i, u := range users {
+		pos := 0
+		for _, field := range []string{u.Name, u.About} {
+			for _, t := range tokenize(field) {
+				p, ok := ix.terms[t]
+				if !ok {
+					p = map[int][]int{}
+					ix.terms[t] = p
+				}
+				p[i] = append(p[i], pos)
+				pos++
+			}
+			pos++
+		}
+		ix.length[i] = pos - 2
+		total += ix.length[i]
+	}
+	if len(users) > 0 {
+		ix.avgLength = float64(total) / float64(len(users))
+	}
+	for t := range ix.terms {
+		ix.vocab = append(ix.vocab, t)
+	}
+	sort.Strings(ix.vocab)
+	return ix
+}
+
+// query is OR of groups of phrases which all have to be found,
+// a single word is a phrase of one term
+type query [][][]string
+
+// parseQuery parses words, "quoted phrases" and OR between them, AND is implied
+// and binds tighter: a b OR c is (a AND b) OR c. it never fails, unclosed quote
+// ends with query and words with dashes or dots are phrases. punctuation is
+// dropped, so query without letters and digits is empty and finds nobody
+func parseQuery(s string) query {
+	q := query{nil}
+	for s != "" {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		var item string
+		quoted := strings.HasPrefix(s, `"`)
+		if quoted {
+			s = s[1:]
+			end := strings.IndexByte(s, '"')
+			if end < 0 {
+				end = len(s)
+			}
+			item, s = s[:end], strings.TrimPrefix(s[end:], `"`)
+		} else {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end < 0 {
+				end = len(s)
+			}
+			item, s = s[:end], s[end:]
+		}
+
+		switch {
+		case !quoted && item == "OR":
+			if len(q[len(q)-1]) > 0 {
+				q = append(q, nil)
+			}
+		case !quoted && item == "AND":
+		default:
+			if p := tokenize(item); len(p) > 0 {
+				q[len(q)-1] = append(q[len(q)-1], p)
+			}
+		}
+	}
+	if len(q[len(q)-1]) == 0 {
+		q = q[:len(q)-1]
+	}
+	return q
+}
+
+// matching returns terms of index which match w, see phrase
+func (ix *index) matching(w string, match func(t, w string) bool) []string {
+	rv := []string{}
+	for _, t := range ix.vocab {
+		if match(t, w) {
+			rv = append(rv, t)
+		}
+	}
+	return rv
+}
+
+func exact(t, w string) bool { return t == w }
+
+// phrase returns users having terms of p one after another and terms it matched.
+// it works like substring search over words: a single word is matched anywhere
+// in a term, the first word of a longer phrase ends a term, the last one starts
+// a term and the ones in between are whole terms
+func (ix *index) phrase(p []string) (map[int]bool, []string) {
+	if len(p) == 1 {
+		terms := ix.matching(p[0], strings.Contains)
+		rv := map[int]bool{}
+		for _, t := range terms {
+			for doc := range ix.terms[t] {
+				rv[doc] = true
+			}
+		}
+		return rv, terms
+	}
+
+	// terms of every word of p
+	words := make([][]string, len(p))
+	words[0] = ix.matching(p[0], strings.HasSuffix)
+	for i := 1; i < len(p)-1; i++ {
+		words[i] = ix.matching(p[i], exact)
+	}
+	words[len(p)-1] = ix.matching(p[len(p)-1], strings.HasPrefix)
+
+	// at tells if one of terms is at pos of doc
+	at := func(terms []string, doc, pos int) bool {
+		for _, t := range terms {
+			ps := ix.terms[t][doc]
+			if k := sort.SearchInts(ps, pos); k < len(ps) && ps[k] == pos {
+				return true
+			}
+		}
+		return false
+	}
+
+	rv := map[int]bool{}
+	used := map[string]bool{}
+	for _, first := range words[0] {
+		for doc, starts := range ix.terms[first] {
+		next:
+			for _, start := range starts {
+				for i := 1; i < len(p); i++ {
+					if !at(words[i], doc, start+i) {
+						continue next
+					}
+				}
+				rv[doc] = true
+				used[first] = true
+				break
+			}
+		}
+	}
+
+	terms := []string{}
+	for t := range used {
+		terms = append(terms, t)
+	}
+	for _, w := range words[1:] {
+		terms = append(terms, w...)
+	}
+	return rv, terms
+}
+
+// search returns users matching q with their bm25 relevance
+func (ix *index) search(q query) map[int]float64 {
+	docs := map[int]bool{}
+	terms := map[string]bool{}
+	for _, group := range q {
+		var found map[int]bool
+		for i, p := range group {
+			m, matched := ix.phrase(p)
+			if i > 0 {
+				for doc := range found {
+					if !m[doc] {
+						delete(found, doc)
+					}
+				}
+			} else {
+				found = m
+			}
+			for _, t := range matched {
+				terms[t] = true
+			}
+		}
+		for doc := range found {
+			docs[doc] = true
+		}
+	}
+
+	scores := map[int]float64{}
+	n := float64(len(ix.length))
+	for doc := range docs {
+		score := 0.0
+		for t := range terms {
+			tf := float64(len(ix.terms[t][doc]))
+			if tf == 0 {
+				continue
+			}
+			df := float64(len(ix.terms[t]))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := 1 - bm25B + bm25B*float64(ix.length[doc])/ix.avgLength
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+		scores[doc] = score
+	}
+	return scores
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		n string
+		s string
+		t []string
+	}{
+		{"1", "Boyd Wolf", []string{"boyd", "wolf"}},
+		{"2", "  Nulla, cillum-enim.\n", []string{"nulla", "cillum", "enim"}},
+		{"3", "ΣΊΣΥΦΟΣ σίσυφος", []string{"σίσυφοσ", "σίσυφοσ"}},
+		{"4", "Kelvin STRAßE", []string{"kelvin", "straße"}},
+		{"5", "!?", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.n, func(t *testing.T) {
+			assert.Equal(t, tt.t, tokenize(tt.s))
+		})
+	}
+}
+
+func TestFold(t *testing.T) {
+	// runes equal for strings.EqualFold are folded to the same one of them
+	for r := rune(0); r <= 0x1ffff; r++ {
+		f := fold(r)
+		if !strings.EqualFold(string(r), string(f)) {
+			t.Fatalf("fold(%U) = %U, not equal fold", r, f)
+		}
+		for o := unicode.SimpleFold(r); o != r; o = unicode.SimpleFold(o) {
+			if fold(o) != f {
+				t.Fatalf("fold(%U) = %U, fold(%U) = %U", r, f, o, fold(o))
+			}
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		n string
+		s string
+		q query
+	}{
+		{"1", "", query{}},
+		{"2", "Boyd", query{{{"boyd"}}}},
+		{"3", "boyd wolf", query{{{"boyd"}, {"wolf"}}}},
+		{"4", "boyd AND wolf OR hilda", query{{{"boyd"}, {"wolf"}}, {{"hilda"}}}},
+		{"5", `"Boyd Wolf" OR "hilda`, query{{{"boyd", "wolf"}}, {{"hilda"}}}},
+		{"6", "OR or OR", query{{{"or"}}}},
+		{"7", "cillum-enim OR !", query{{{"cillum", "enim"}}}},
+		{"8", `"OR"`, query{{{"or"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.n, func(t *testing.T) {
+			assert.Equal(t, tt.q, parseQuery(tt.s))
+		})
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	ix := newIndex([]User{
+		{Id: 0, Name: "Boyd Wolf", About: "nulla cillum enim"},
+		{Id: 1, Name: "Hilda Wolf", About: "Wolf nulla wolf"},
+		{Id: 2, Name: "Nulla Boyd", About: "cillum boyd"},
+		{Id: 3, Name: "ΣΊΣΥΦΟΣ", About: ""},
+	})
+
+	tests := []struct {
+		n    string
+		q    string
+		docs []int
+	}{
+		{"term", "wolf", []int{1, 0}},
+		{"case", "NULLA", []int{2, 0, 1}},
+		{"unicode case", "σίσυφος", []int{3}},
+		{"and", "wolf nulla", []int{1, 0}},
+		{"or", "hilda OR cillum", []int{1, 2, 0}},
+		{"phrase", `"boyd wolf"`, []int{0}},
+		{"phrase between fields", `"wolf nulla"`, []int{1}},
+		{"phrase order", `"wolf boyd"`, []int{}},
+		{"phrase and term", `"cillum boyd" nulla`, []int{2}},
+		{"nothing", "bear", []int{}},
+		{"substring", "ULL", []int{2, 0, 1}},
+		{"substring of unicode", "ΣΥΦ", []int{3}},
+		{"phrase of substrings", `"oyd wo"`, []int{0}},
+		{"phrase with whole middle word", `"olf nulla cill"`, []int{}},
+		{"phrase middle word", `"wolf nulla wo"`, []int{1}},
+		{"punctuation", ",", []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.n, func(t *testing.T) {
+			scores := ix.search(parseQuery(tt.q))
+			docs := []int{}
+			for doc := range scores {
+				docs = append(docs, doc)
+			}
+			// most relevant first
+			sort.Slice(docs, func(i, j int) bool {
+				if scores[docs[i]] != scores[docs[j]] {
+					return scores[docs[i]] > scores[docs[j]]
+				}
+				return docs[i] < docs[j]
+			})
+			assert.Equal(t, tt.docs, docs)
+		})
+	}
+}
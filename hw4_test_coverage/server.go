@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -21,6 +22,7 @@ var (
 	ErrorWrongQuery     = fmt.Errorf("query is wrong")
 	ErrorInvalidOrder   = fmt.Errorf("ErrorBadOrderField")
 	ErrorInvalidOrderby = fmt.Errorf("should be [-1:1]")
+	ErrorBadCursor      = fmt.Errorf("ErrorBadCursor")
 )
 
 type root struct {
@@ -42,6 +44,10 @@ type server struct {
 	token string
 	// users data
 	users []User
+	// key of cursors hmac, cursors of previous runs are invalid
+	secret []byte
+	// query index of users
+	index *index
 }
 
 func NewServer(token string, fp string) (s *server, e error) {
@@ -58,10 +64,15 @@ func NewServer(token string, fp string) (s *server, e error) {
 
 	rv := &server{}
 	rv.token = token
+	rv.secret = make([]byte, 32)
+	if _, err := rand.Read(rv.secret); err != nil {
+		return nil, errors.Wrap(err, "couldnt make cursor key")
+	}
 	rv.users = make([]User, 0)
 	for _, u := range root.Users {
 		rv.users = append(rv.users, *u.Convert())
 	}
+	rv.index = newIndex(rv.users)
 
 	s = rv
 
@@ -78,6 +89,14 @@ func (r *row) Convert() *User {
 	}
 }
 
+// request is SearchRequest as server gets it, with paging state client doesnt share
+type request struct {
+	SearchRequest
+	// page after cursor instead of Offset, empty cursor is the first page
+	cursor   string
+	byCursor bool
+}
+
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if s.token != r.Header.Get("AccessToken") {
 		w.WriteHeader(http.StatusUnauthorized)
@@ -97,13 +116,13 @@ func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-func (s *server) SearchRequest(req string) (*SearchRequest, error) {
+func (s *server) SearchRequest(req string) (*request, error) {
 	q, err := url.ParseQuery(req)
 	if err != nil {
 		return nil, errors.Wrap(ErrorWrongQuery, "couldnt parse req")
 	}
 
-	sr := &SearchRequest{}
+	sr := &request{}
 
 	if q.Get("limit") == "" {
 		return nil, errors.Wrap(ErrorMissedField, "limit")
@@ -116,18 +135,27 @@ func (s *server) SearchRequest(req string) (*SearchRequest, error) {
 		return nil, errors.Wrap(ErrorWrongValue, "limit should be positive")
 	}
 
-	if q.Get("offset") == "" {
-		return nil, errors.Wrap(ErrorMissedField, "field")
-	}
-	sr.Offset, err = strconv.Atoi(q.Get("offset"))
-	if err != nil {
-		return nil, errors.Wrap(ErrorStrIntCast, "offset")
-	}
-	if sr.Offset < 0 {
-		return nil, errors.Wrap(ErrorWrongValue, "offset should be positive")
+	// cursor replaces offset, it is empty for the first page
+	_, sr.byCursor = q["cursor"]
+	sr.cursor = q.Get("cursor")
+	if sr.byCursor && q.Get("offset") != "" {
+		return nil, errors.Wrap(ErrorWrongQuery, "offset and cursor together")
 	}
-	if sr.Offset > len(s.users) {
-		return nil, errors.Wrap(ErrorWrongValue, "offset is too big")
+
+	if !sr.byCursor {
+		if q.Get("offset") == "" {
+			return nil, errors.Wrap(ErrorMissedField, "field")
+		}
+		sr.Offset, err = strconv.Atoi(q.Get("offset"))
+		if err != nil {
+			return nil, errors.Wrap(ErrorStrIntCast, "offset")
+		}
+		if sr.Offset < 0 {
+			return nil, errors.Wrap(ErrorWrongValue, "offset should be positive")
+		}
+		if sr.Offset > len(s.users) {
+			return nil, errors.Wrap(ErrorWrongValue, "offset is too big")
+		}
 	}
 
 	sr.OrderBy, err = strconv.Atoi(q.Get("order_by"))
@@ -135,8 +163,13 @@ func (s *server) SearchRequest(req string) (*SearchRequest, error) {
 		return nil, errors.Wrap(ErrorStrIntCast, "order_by")
 	}
 
-	if sr.Limit > 25 {
-		sr.Limit = 25
+	// FindUsers asks for a user more than the page to know if there is the next one
+	maxLimit := 25
+	if !sr.byCursor {
+		maxLimit++
+	}
+	if sr.Limit > maxLimit {
+		sr.Limit = maxLimit
 	}
 
 	switch sr.OrderBy {
@@ -150,16 +183,12 @@ func (s *server) SearchRequest(req string) (*SearchRequest, error) {
 	sr.Query = q.Get("query")
 	sr.OrderField = q.Get("order_field")
 
-	switch sr.OrderField {
-	case "":
-		fallthrough
-	case "Id":
-		fallthrough
-	case "Age":
-		fallthrough
-	case "Name":
-	default:
-		return nil, errors.Wrap(ErrorInvalidOrder, sr.OrderField)
+	if _, err := parseOrder(sr.OrderField, sr.OrderBy); err != nil {
+		return nil, err
+	}
+
+	if err := parseFilters(q, &sr.SearchRequest); err != nil {
+		return nil, err
 	}
 
 	return sr, nil
@@ -186,40 +215,142 @@ func sortbyag(a, b User, inc int) bool {
 	return a.Age > b.Age
 }
 
-func sortUsers(u []User, order string, inc int) []User {
-	if inc == OrderByAsIs {
-		return u
+// orderKey is a field users are sorted by and its direction
+type orderKey struct {
+	less func(User, User, int) bool
+	inc  int
+}
+
+type orderKeys []orderKey
+
+// parseOrder parses order_field, fields separated by comma in order_by direction,
+// - before a field reverses it. empty order is by Name
+func parseOrder(order string, inc int) (orderKeys, error) {
+	if order == "" {
+		order = "Name"
+	}
+
+	rv := orderKeys{}
+	seen := map[string]bool{}
+	for _, f := range strings.Split(order, ",") {
+		k := orderKey{inc: inc}
+		if strings.HasPrefix(f, "-") {
+			f, k.inc = f[1:], -inc
+		}
+		switch f {
+		case "Id":
+			k.less = sortbyid
+		case "Age":
+			k.less = sortbyag
+		case "Name":
+			k.less = sortbynm
+		default:
+			return nil, errors.Wrap(ErrorInvalidOrder, order)
+		}
+		if seen[f] {
+			return nil, errors.Wrap(ErrorInvalidOrder, order)
+		}
+		seen[f] = true
+		rv = append(rv, k)
 	}
+	return rv, nil
+}
+
+// before tells if a goes before b, users equal by all keys are ordered by Id
+func (keys orderKeys) before(a, b User) bool {
+	for _, k := range keys {
+		if k.less(a, b, k.inc) {
+			return true
+		}
+		if k.less(b, a, k.inc) {
+			return false
+		}
+	}
+	return a.Id < b.Id
+}
 
-	var sorter func(User, User, int) bool
+// order returns comparator of found users, nil keeps them as they are.
+// users found by query are ranked by relevance if order is as is
+func order(sc *SearchRequest, scores map[int]float64) func(a, b User) bool {
+	if sc.OrderBy != OrderByAsIs {
+		// order is checked by SearchRequest
+		keys, _ := parseOrder(sc.OrderField, sc.OrderBy)
+		return keys.before
+	}
+	if scores != nil {
+		return func(a, b User) bool {
+			if scores[a.Id] != scores[b.Id] {
+				return scores[a.Id] > scores[b.Id]
+			}
+			return a.Id < b.Id
+		}
+	}
+	return nil
+}
 
-	switch order {
-	case "Id":
-		sorter = sortbyid
-	case "Age":
-		sorter = sortbyag
-	case "":
-		fallthrough
-	case "Name":
-		sorter = sortbynm
+func sortUsers(u []User, less func(a, b User) bool) []User {
+	if less == nil {
+		return u
 	}
 
-	sort.Slice(u, func(i, j int) bool { return sorter(u[i], u[j], inc) })
+	// order is total, so pages dont overlap
+	sort.SliceStable(u, func(i, j int) bool { return less(u[i], u[j]) })
 
 	return u
 }
 
-func (s *server) SearchUsers(sc *SearchRequest) ([]byte, int) {
+// page returns limit users starting from offset, a part of it or nothing near the end
+func page(u []User, offset, limit int) []User {
+	if offset > len(u) {
+		offset = len(u)
+	}
+	if offset+limit > len(u) {
+		limit = len(u) - offset
+	}
+	return u[offset : offset+limit]
+}
+
+// find returns users matching query and filters in dataset order and relevance
+// of them by Id, which is nil if there is no query
+func (s *server) find(sc *SearchRequest) ([]User, map[int]float64) {
 	rv := make([]User, 0)
 
-	for i := sc.Offset; i < sc.Limit; i++ {
-		if !strings.Contains(s.users[i].Name, sc.Query) && !strings.Contains(s.users[i].About, sc.Query) {
-			continue
+	if sc.Query == "" {
+		for i := range s.users {
+			if sc.match(&s.users[i]) {
+				rv = append(rv, s.users[i])
+			}
+		}
+		return rv, nil
+	}
+
+	found := s.index.search(parseQuery(sc.Query))
+	docs := make([]int, 0, len(found))
+	for doc := range found {
+		docs = append(docs, doc)
+	}
+	sort.Ints(docs)
+
+	scores := map[int]float64{}
+	for _, doc := range docs {
+		if u := &s.users[doc]; sc.match(u) {
+			rv = append(rv, *u)
+			scores[u.Id] = found[doc]
 		}
-		rv = append(rv, s.users[i])
+	}
+	return rv, scores
+}
+
+// SearchUsers finds all users by query and filters, sorts them and only then cuts the page
+func (s *server) SearchUsers(sc *request) ([]byte, int) {
+	rv, scores := s.find(&sc.SearchRequest)
+
+	if sc.byCursor {
+		return s.searchByCursor(rv, scores, sc)
 	}
 
-	rv = sortUsers(rv, sc.OrderField, sc.OrderBy)
+	rv = sortUsers(rv, order(&sc.SearchRequest, scores))
+	rv = page(rv, sc.Offset, sc.Limit)
 
 	js, _ := json.Marshal(rv)
 
@@ -1,6 +1,11 @@
 package main
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -26,7 +31,7 @@ func TestSearchRequest(t *testing.T) {
 	tests := []struct {
 		n string
 		q string
-		r *SearchRequest
+		r *request
 		e error
 	}{
 		{"1", "&;#=?", nil, ErrorWrongQuery},
@@ -38,8 +43,25 @@ func TestSearchRequest(t *testing.T) {
 		{"7", "offset=ss&limit=1&order_by=1", nil, ErrorStrIntCast},
 		{"8", "offset=1&limit=ss&order_by=1", nil, ErrorStrIntCast},
 		{"9", "offset=100&limit=10&order_by=1", nil, ErrorWrongValue},
-		{"10", "offset=5&limit=1&order_by=1", &SearchRequest{Offset: 5, Limit: 1, OrderBy: 1}, nil},
-		{"11", "offset=5&limit=30&order_by=1&order_field=Age", &SearchRequest{Offset: 5, Limit: 25, OrderBy: 1, OrderField: "Age"}, nil},
+		{"10", "offset=5&limit=1&order_by=1", &request{SearchRequest: SearchRequest{Offset: 5, Limit: 1, OrderBy: 1}}, nil},
+		{"11", "offset=5&limit=30&order_by=1&order_field=Age", &request{SearchRequest: SearchRequest{Offset: 5, Limit: 26, OrderBy: 1, OrderField: "Age"}}, nil},
+		{"12", "cursor=&limit=5&order_by=0", &request{SearchRequest: SearchRequest{Limit: 5}, byCursor: true}, nil},
+		{"13", "cursor=abc&limit=5&order_by=0", &request{SearchRequest: SearchRequest{Limit: 5}, cursor: "abc", byCursor: true}, nil},
+		{"14", "cursor=abc&offset=1&limit=5&order_by=0", nil, ErrorWrongQuery},
+		{"15", "cursor=&limit=30&order_by=0", &request{SearchRequest: SearchRequest{Limit: 25}, byCursor: true}, nil},
+		{"16", "offset=0&limit=5&order_by=1&order_field=Age,-Name,Id", &request{SearchRequest: SearchRequest{Limit: 5, OrderBy: 1, OrderField: "Age,-Name,Id"}}, nil},
+		{"17", "offset=0&limit=5&order_by=1&order_field=Age,,Id", nil, ErrorInvalidOrder},
+		{"18", "offset=0&limit=5&order_by=1&order_field=Age,-Age", nil, ErrorInvalidOrder},
+		{"19", "offset=0&limit=5&order_by=1&order_field=-", nil, ErrorInvalidOrder},
+		{"20", "offset=0&limit=5&order_by=1&order_field=age", nil, ErrorInvalidOrder},
+		{"21", "offset=0&limit=5&order_by=1&order_field=Age,Name ", nil, ErrorInvalidOrder},
+		{"22", "offset=0&limit=5&order_by=0&gender=female&age_min=20&age_max=30&id_in=3,1", &request{SearchRequest: SearchRequest{Limit: 5, Gender: "female", AgeMin: 20, AgeMax: 30, IdIn: []int{3, 1}}}, nil},
+		{"23", "offset=0&limit=5&order_by=0&gender=Female", nil, ErrorWrongValue},
+		{"24", "offset=0&limit=5&order_by=0&age_min=x", nil, ErrorStrIntCast},
+		{"25", "offset=0&limit=5&order_by=0&age_max=-1", nil, ErrorWrongValue},
+		{"26", "offset=0&limit=5&order_by=0&age_min=30&age_max=20", nil, ErrorWrongValue},
+		{"27", "offset=0&limit=5&order_by=0&id_in=1,,2", nil, ErrorStrIntCast},
+		{"28", "offset=0&limit=5&order_by=0&age_min=30", &request{SearchRequest: SearchRequest{Limit: 5, AgeMin: 30}}, nil},
 	}
 	for _, tt := range tests {
 		t.Run(tt.n, func(t *testing.T) {
@@ -49,3 +71,106 @@ func TestSearchRequest(t *testing.T) {
 		})
 	}
 }
+
+func ids(u []User) []int {
+	rv := []int{}
+	for _, u := range u {
+		rv = append(rv, u.Id)
+	}
+	return rv
+}
+
+func TestSearchUsers(t *testing.T) {
+	s, e := NewServer(AccessToken, "dataset.xml")
+	assert.NotNil(t, s)
+	assert.NoError(t, e)
+
+	tests := []struct {
+		n string
+		r SearchRequest
+		u []int
+	}{
+		{"first page", SearchRequest{Limit: 3, OrderField: "Id", OrderBy: OrderByDesc}, []int{0, 1, 2}},
+		{"offset past limit", SearchRequest{Offset: 10, Limit: 3, OrderField: "Id", OrderBy: OrderByDesc}, []int{10, 11, 12}},
+		{"last page", SearchRequest{Offset: 33, Limit: 3, OrderField: "Id", OrderBy: OrderByDesc}, []int{33, 34}},
+		{"past the end", SearchRequest{Offset: 35, Limit: 3, OrderField: "Id", OrderBy: OrderByDesc}, []int{}},
+		{"zero limit", SearchRequest{Limit: 0, OrderField: "Id", OrderBy: OrderByDesc}, []int{}},
+		{"sorted before page", SearchRequest{Limit: 2, OrderField: "Id", OrderBy: OrderByAsc}, []int{34, 33}},
+		{"ties by id", SearchRequest{Limit: 4, OrderField: "Age", OrderBy: OrderByDesc}, []int{1, 15, 23, 0}},
+		{"two keys", SearchRequest{Limit: 4, OrderField: "Age,-Name", OrderBy: OrderByDesc}, []int{1, 23, 15, 0}},
+		{"two keys reversed", SearchRequest{Limit: 4, OrderField: "Age,-Name", OrderBy: OrderByAsc}, []int{32, 13, 6, 26}},
+		{"reversed id", SearchRequest{Limit: 3, OrderField: "-Id", OrderBy: OrderByDesc}, []int{34, 33, 32}},
+		{"gender", SearchRequest{Limit: 25, Gender: "female"}, []int{1, 5, 7, 9, 16, 22, 25, 27, 29, 32, 33}},
+		{"age min", SearchRequest{Limit: 25, AgeMin: 39}, []int{6, 13, 26, 32}},
+		{"age range", SearchRequest{Limit: 25, AgeMin: 21, AgeMax: 21}, []int{1, 15, 23}},
+		{"gender and age max", SearchRequest{Limit: 25, Gender: "male", AgeMax: 22}, []int{0, 15, 23}},
+		{"ids", SearchRequest{Limit: 25, IdIn: []int{3, 1, 40}}, []int{1, 3}},
+		{"ids and query", SearchRequest{Limit: 25, IdIn: []int{11, 12}, Query: "Cruz"}, []int{12}},
+		{"ranked", SearchRequest{Limit: 3, Query: "Boyd OR Hilda"}, []int{1, 0}},
+		{"ranked page", SearchRequest{Offset: 1, Limit: 3, Query: "nulla"}, []int{0, 1, 7}},
+		{"query sorted by order", SearchRequest{Limit: 3, Query: "Boyd OR Hilda", OrderField: "Id", OrderBy: OrderByDesc}, []int{0, 1}},
+		{"case insensitive", SearchRequest{Limit: 3, Query: "boyd WOLF"}, []int{0}},
+		{"substring", SearchRequest{Limit: 25, Query: "ILL", OrderField: "Id", OrderBy: OrderByDesc}, []int{0, 2, 3, 5, 8, 10, 13, 15, 17, 18, 19, 22, 24, 26, 27, 31, 32, 33, 34}},
+		{"punctuation only", SearchRequest{Limit: 25, Query: ","}, []int{}},
+		{"query over all users", SearchRequest{Limit: 25, Query: "Twila"}, []int{33}},
+		{"query page", SearchRequest{Offset: 1, Limit: 1, Query: "Guerr", OrderField: "Name", OrderBy: OrderByDesc}, []int{11}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.n, func(t *testing.T) {
+			data, code := s.SearchUsers(&request{SearchRequest: tt.r})
+			assert.Equal(t, http.StatusOK, code)
+			u := []User{}
+			assert.NoError(t, json.Unmarshal(data, &u))
+			assert.Equal(t, tt.u, ids(u))
+		})
+	}
+}
+
+func TestSearchByCursor(t *testing.T) {
+	s, e := NewServer(AccessToken, "dataset.xml")
+	assert.NotNil(t, s)
+	assert.NoError(t, e)
+
+	search := func(q string) (*SearchCursorResponse, string) {
+		sc, err := s.SearchRequest(q)
+		assert.NoError(t, err)
+		data, code := s.SearchUsers(sc)
+		if code != http.StatusOK {
+			errResp := SearchErrorResponse{}
+			assert.NoError(t, json.Unmarshal(data, &errResp))
+			return nil, errResp.Error
+		}
+		rv := &SearchCursorResponse{}
+		assert.NoError(t, json.Unmarshal(data, rv))
+		return rv, ""
+	}
+
+	first, _ := search("cursor=&limit=3&order_by=-1&order_field=Age")
+	assert.Equal(t, []int{13, 32, 6}, ids(first.Users))
+	assert.NotEmpty(t, first.NextCursor)
+
+	second, _ := search("limit=3&order_by=-1&order_field=Age&cursor=" + first.NextCursor)
+	assert.Equal(t, []int{26, 31, 9}, ids(second.Users))
+
+	// user the cursor points after is found by its fields, not by position
+	s.users = s.users[7:]
+	s.index = newIndex(s.users)
+	again, _ := search("limit=3&order_by=-1&order_field=Age&cursor=" + first.NextCursor)
+	assert.Equal(t, second, again)
+
+	payload, sig, _ := strings.Cut(first.NextCursor, ".")
+	forged, _ := json.Marshal(cursor{Id: 0, Age: 99, Req: requestHash(&SearchRequest{OrderField: "Age", OrderBy: -1})})
+	for _, c := range []string{
+		"abc",
+		payload,
+		payload + "." + sig[1:],
+		base64.RawURLEncoding.EncodeToString(forged) + "." + sig,
+		"!." + sig,
+	} {
+		_, err := search("limit=3&order_by=-1&order_field=Age&cursor=" + url.QueryEscape(c))
+		assert.Equal(t, ErrorBadCursor.Error(), err, c)
+	}
+
+	_, err := search("limit=3&order_by=1&order_field=Age&cursor=" + first.NextCursor)
+	assert.Equal(t, ErrorBadCursor.Error(), err, "cursor of another order")
+}